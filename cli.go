@@ -0,0 +1,159 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dave/ght/pipeline"
+	"github.com/dave/ght/project"
+	"github.com/dave/ght/stats"
+)
+
+var commands = []struct {
+	Name, Usage string
+	Run         func(*project.Trail, pipeline.Options) error
+	Flags       []string // the flags the command reads, as well as -config and -trail
+}{
+	{"sync", "download the trail notes from the google sheet", pipeline.Sync, []string{"dry-run"}},
+	{"dem", "correct the elevations in the GPX files from DEM tiles and report the largest changes", pipeline.CorrectElevations, []string{"legs", "dry-run"}},
+	{"import", "merge the routes and waypoints of a KML or KMZ file edited in Google Earth into the GPX files", pipeline.ImportKML, []string{"kml", "legs", "dry-run"}},
+	{"validate", "check the trail notes are valid and their waypoints and passes match the GPX files", pipeline.Validate, []string{"legs"}},
+	{"stats", "calculate length, climb, descent and walking time for each leg and write them to the trail notes", pipeline.CalcStats, []string{"legs", "dry-run", "climb", "walking", "format", "sheet"}},
+	{"routes", "process final routes and output new GPX, KML and GeoJSON files, and one for each export profile (remember to increment version)", pipeline.ProcessFinalRoutesAll, []string{"version", "legs", "dry-run"}},
+	{"downloads", "write GPX and KML files of each leg and section, and a manifest of their sizes and checksums", pipeline.WriteDownloads, []string{"version", "legs", "dry-run"}},
+	{"routes-page", "create the GPS routes page listing the routes files, their sizes and the changelog", pipeline.CreateRoutesPage, []string{"version", "dry-run"}},
+	{"diff", "write the changes to the routes and trail notes since an earlier version as markdown", pipeline.Diff, []string{"version", "since", "old-notes", "threshold", "legs", "climb", "walking"}},
+	{"notes", "create the trail notes pages", pipeline.CreateTrailNotes, []string{"version", "legs", "dry-run", "climb", "walking"}},
+	{"maps", "create map images for trail notes", pipeline.DrawMaps, []string{"legs", "dry-run"}},
+	{"elevations", "create elevation graphs for trail notes", pipeline.DrawElevations, []string{"legs", "dry-run"}},
+	{"kmz", "write a KMZ file of the routes with the map and elevation images of each leg, for Google Earth", pipeline.WriteKMZ, []string{"version", "legs", "dry-run"}},
+	{"tours", "write a Google Earth tour of each leg, flying along the route and pausing at waypoints and passes", pipeline.WriteTours, []string{"version", "legs", "dry-run"}},
+	{"all", "run routes, downloads, routes-page, notes, maps, elevations and kmz", pipeline.RunAll, []string{"version", "legs", "dry-run", "climb", "walking"}},
+}
+
+// flags registers each of the optional flags on the flag set of a command that reads it.
+var flags = map[string]func(fs *flag.FlagSet, opts *pipeline.Options){
+	"version": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.IntVar(&opts.Version, "version", 0, "version number of the routes and trail notes (default the latest of the trail's releases)")
+	},
+	"legs": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.Var(opts.Legs, "legs", "legs to process, e.g. 44 or 40-52,60 (default all legs)")
+	},
+	"dry-run": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.BoolVar(&opts.DryRun, "dry-run", false, "do all the processing but don't write any files")
+	},
+	"climb": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.StringVar(&opts.Climb, "climb", "", "climb algorithm: "+climbAlgorithms()+" (default the trail's climb setting, or "+stats.Algorithms[0].Name+")")
+	},
+	"walking": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.StringVar(&opts.Walking, "walking", "", "walking time model: "+timeModels()+" (default the trail's walking setting, or "+stats.TimeModels[0].Name+")")
+	},
+	"format": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.StringVar(&opts.Format, "format", "", "write the stats of every leg to stdout as csv, json or markdown")
+	},
+	"since": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.IntVar(&opts.Since, "since", 0, "version to compare the routes with (default the previous version)")
+	},
+	"old-notes": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.StringVar(&opts.OldNotes, "old-notes", "", "trail notes JSON of the earlier version to compare the notes with")
+	},
+	"threshold": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.Float64Var(&opts.Threshold, "threshold", 50, "distance in m a route or waypoint must move to be reported")
+	},
+	"kml": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.StringVar(&opts.Import, "kml", "", "KML or KMZ file to merge into the GPX files")
+	},
+	"sheet": func(fs *flag.FlagSet, opts *pipeline.Options) {
+		fs.BoolVar(&opts.Sheet, "sheet", false, "write to the google sheet as well as the trail notes")
+	},
+}
+
+func climbAlgorithms() string {
+	var names []string
+	for _, a := range stats.Algorithms {
+		names = append(names, a.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func timeModels() string {
+	var names []string
+	for _, m := range stats.TimeModels {
+		names = append(names, m.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: ght <command> [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.Name, c.Usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun \"ght <command> -h\" for the flags of a command.\n")
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		usage()
+		return fmt.Errorf("no command given")
+	}
+	name := args[0]
+	if name == "help" || name == "-h" || name == "-help" || name == "--help" {
+		usage()
+		return nil
+	}
+	for _, c := range commands {
+		if c.Name != name {
+			continue
+		}
+		opts := pipeline.Options{Legs: pipeline.LegSet{}}
+		fs := flag.NewFlagSet(c.Name, flag.ContinueOnError)
+		configFile := fs.String("config", "ght.yaml", "project config file")
+		trail := fs.String("trail", "", "slug of the trail to process (default all trails in the project)")
+		var version bool
+		for _, f := range c.Flags {
+			flags[f](fs, &opts)
+			version = version || f == "version"
+		}
+		if err := fs.Parse(args[1:]); err != nil {
+			if err == flag.ErrHelp {
+				return nil
+			}
+			return err
+		}
+		if fs.NArg() > 0 {
+			return fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+		}
+		p, err := project.Load(*configFile)
+		if err != nil {
+			return err
+		}
+		trails := p.Trails
+		if *trail != "" {
+			t, err := p.Trail(*trail)
+			if err != nil {
+				return err
+			}
+			trails = []*project.Trail{t}
+		}
+		for _, t := range trails {
+			if len(trails) > 1 {
+				fmt.Printf("%s\n", t.Name)
+			}
+			opts := opts
+			if version && opts.Version == 0 {
+				if opts.Version = t.LatestVersion(); opts.Version == 0 {
+					return fmt.Errorf("%s: no -version given and the trail has no releases", t.Slug)
+				}
+			}
+			if err := c.Run(t, opts); err != nil {
+				return fmt.Errorf("%s: %w", t.Slug, err)
+			}
+		}
+		return nil
+	}
+	usage()
+	return fmt.Errorf("unknown command %q", name)
+}
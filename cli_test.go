@@ -0,0 +1,37 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	// each command only accepts the flags it reads
+	if err := run([]string{"maps", "-threshold", "5"}); err == nil || !strings.Contains(err.Error(), "-threshold") {
+		t.Errorf("got error %v, want -threshold not defined", err)
+	}
+	for _, c := range commands {
+		for _, f := range c.Flags {
+			if flags[f] == nil {
+				t.Errorf("%s: unknown flag %q", c.Name, f)
+			}
+		}
+	}
+}
+
+func TestVersion(t *testing.T) {
+	config := filepath.Join(t.TempDir(), "ght.yaml")
+	if err := ioutil.WriteFile(config, []byte(`trails:
+  - name: Great Himalaya Trail
+    slug: great-himalaya-trail
+    notes: trailnotes.json
+    gpx: data/gpx
+`), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := run([]string{"routes", "-config", config}); err == nil || !strings.Contains(err.Error(), "no -version given") {
+		t.Errorf("got error %v, want no -version given", err)
+	}
+}
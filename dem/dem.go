@@ -0,0 +1,113 @@
+// Package dem samples elevations from digital elevation model tiles on disk: SRTM .hgt files and
+// GeoTIFFs in latitude and longitude.
+package dem
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// DEM is a set of tiles, loaded the first time a point in them is sampled.
+type DEM struct {
+	tiles []*tile
+}
+
+type tile struct {
+	filename                 string
+	west, south, east, north float64
+	load                     func() (*grid, error)
+	grid                     *grid
+}
+
+// grid is the samples of a tile.
+type grid struct {
+	width, height int
+	west, north   float64 // longitude and latitude of the top left sample
+	dx, dy        float64 // degrees between samples
+	value         func(x, y int) (float64, bool)
+}
+
+// Open finds the .hgt, .tif and .tiff tiles in a directory.
+func Open(dir string) (*DEM, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	d := &DEM{}
+	for _, file := range files {
+		fpath := filepath.Join(dir, file.Name())
+		var t *tile
+		switch strings.ToLower(filepath.Ext(file.Name())) {
+		case ".hgt":
+			t, err = openHgt(fpath)
+		case ".tif", ".tiff":
+			t, err = openGeoTiff(fpath)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error opening DEM tile %q: %w", fpath, err)
+		}
+		d.tiles = append(d.tiles, t)
+	}
+	if len(d.tiles) == 0 {
+		return nil, fmt.Errorf("no .hgt or GeoTIFF tiles in %q", dir)
+	}
+	return d, nil
+}
+
+// Elevation returns the elevation at a point, interpolated from the four samples around it. It
+// returns false if no tile covers the point or the samples are voids.
+func (d *DEM) Elevation(lat, lon float64) (float64, bool, error) {
+	for _, t := range d.tiles {
+		if lat < t.south || lat > t.north || lon < t.west || lon > t.east {
+			continue
+		}
+		if t.grid == nil {
+			g, err := t.load()
+			if err != nil {
+				return 0, false, fmt.Errorf("error loading DEM tile %q: %w", t.filename, err)
+			}
+			t.grid = g
+		}
+		if ele, ok := t.grid.sample(lat, lon); ok {
+			return ele, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+// sample interpolates bilinearly between the four samples around a point. Points within half a
+// sample of the edge use the edge samples.
+func (g *grid) sample(lat, lon float64) (float64, bool) {
+	clamp := func(f float64, max int) float64 {
+		return math.Max(0, math.Min(f, float64(max-1)))
+	}
+	fx := clamp((lon-g.west)/g.dx, g.width)
+	fy := clamp((g.north-lat)/g.dy, g.height)
+	x0, y0 := int(fx), int(fy)
+	tx, ty := fx-float64(x0), fy-float64(y0)
+	// samples with no weight aren't needed, so a point on a sample next to a void has an elevation
+	x1, y1 := x0+1, y0+1
+	if x1 >= g.width || tx == 0 {
+		x1 = x0
+	}
+	if y1 >= g.height || ty == 0 {
+		y1 = y0
+	}
+
+	var v [4]float64
+	for i, p := range [4][2]int{{x0, y0}, {x1, y0}, {x0, y1}, {x1, y1}} {
+		value, ok := g.value(p[0], p[1])
+		if !ok {
+			return 0, false
+		}
+		v[i] = value
+	}
+	top := v[0]*(1-tx) + v[1]*tx
+	bottom := v[2]*(1-tx) + v[3]*tx
+	return top*(1-ty) + bottom*ty, true
+}
@@ -0,0 +1,410 @@
+package dem
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// writeHgt writes an SRTM tile of size x size samples, given from the north west corner.
+func writeHgt(t *testing.T, dir, name string, size int, values []int16) {
+	t.Helper()
+	b := make([]byte, 2*size*size)
+	for i, v := range values {
+		binary.BigEndian.PutUint16(b[2*i:], uint16(v))
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// geoTiff describes a little endian GeoTIFF of 16 bit integers or 32 bit floats for writeGeoTiff.
+type geoTiff struct {
+	width, height  int
+	values         []int16 // from the north west corner
+	rowsPerStrip   int     // for strips
+	tileW, tileH   int     // for tiles, if set
+	deflate        bool
+	predictor      bool // horizontal differencing
+	pixelIsPoint   bool
+	projected      bool
+	noData         string
+	west, north    float64 // tiepoint
+	dx, dy         float64
+	floatingPoint  bool // floatingValues instead of values
+	floatingValues []float32
+}
+
+type ifdEntry struct {
+	tag, typ uint16
+	count    int
+	data     []byte
+}
+
+func shorts(tag uint16, values ...int) ifdEntry {
+	b := make([]byte, 2*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint16(b[2*i:], uint16(v))
+	}
+	return ifdEntry{tag, 3, len(values), b}
+}
+
+func longs(tag uint16, values ...int) ifdEntry {
+	b := make([]byte, 4*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(b[4*i:], uint32(v))
+	}
+	return ifdEntry{tag, 4, len(values), b}
+}
+
+func doubles(tag uint16, values ...float64) ifdEntry {
+	b := make([]byte, 8*len(values))
+	for i, v := range values {
+		binary.LittleEndian.PutUint64(b[8*i:], math.Float64bits(v))
+	}
+	return ifdEntry{tag, 12, len(values), b}
+}
+
+func writeGeoTiff(t *testing.T, dir, name string, g geoTiff) {
+	t.Helper()
+	size := 2
+	if g.floatingPoint {
+		size = 4
+	}
+	sample := func(x, y int) []byte {
+		b := make([]byte, size)
+		if x >= g.width || y >= g.height {
+			return b // padding of partial tiles
+		}
+		if g.floatingPoint {
+			binary.LittleEndian.PutUint32(b, math.Float32bits(g.floatingValues[y*g.width+x]))
+		} else {
+			binary.LittleEndian.PutUint16(b, uint16(g.values[y*g.width+x]))
+		}
+		return b
+	}
+
+	// the blocks, strips as wide as the image or tiles
+	blockW, blockH := g.width, g.rowsPerStrip
+	if g.tileW > 0 {
+		blockW, blockH = g.tileW, g.tileH
+	}
+	var blocks [][]byte
+	for y0 := 0; y0 < g.height; y0 += blockH {
+		for x0 := 0; x0 < g.width; x0 += blockW {
+			var block []byte
+			for y := y0; y < y0+blockH && (g.tileW > 0 || y < g.height); y++ {
+				previous := int16(0)
+				for x := x0; x < x0+blockW; x++ {
+					b := sample(x, y)
+					if g.predictor {
+						v := int16(binary.LittleEndian.Uint16(b))
+						binary.LittleEndian.PutUint16(b, uint16(v-previous))
+						previous = v
+					}
+					block = append(block, b...)
+				}
+			}
+			if g.deflate {
+				buf := &bytes.Buffer{}
+				w := zlib.NewWriter(buf)
+				w.Write(block)
+				w.Close()
+				block = buf.Bytes()
+			}
+			blocks = append(blocks, block)
+		}
+	}
+
+	b := []byte("II*\x00\x00\x00\x00\x00")
+	var offsets, counts []int
+	for _, block := range blocks {
+		offsets = append(offsets, len(b))
+		counts = append(counts, len(block))
+		b = append(b, block...)
+	}
+
+	compression, predictor, format := 1, 1, 2
+	if g.deflate {
+		compression = 8
+	}
+	if g.predictor {
+		predictor = 2
+	}
+	if g.floatingPoint {
+		format = 3
+	}
+	model, raster := 2, 1
+	if g.projected {
+		model = 1
+	}
+	if g.pixelIsPoint {
+		raster = 2
+	}
+	entries := []ifdEntry{
+		longs(tagImageWidth, g.width),
+		longs(tagImageLength, g.height),
+		shorts(tagBitsPerSample, 8*size),
+		shorts(tagCompression, compression),
+		shorts(tagSamplesPerPixel, 1),
+		shorts(tagPredictor, predictor),
+		shorts(tagSampleFormat, format),
+		doubles(tagPixelScale, g.dx, g.dy, 0),
+		doubles(tagTiepoint, 0, 0, 0, g.west, g.north, 0),
+		shorts(tagGeoKeys, 1, 1, 0, 2, 1024, 0, 1, model, 1025, 0, 1, raster),
+	}
+	if g.tileW > 0 {
+		entries = append(entries, longs(tagTileWidth, g.tileW), longs(tagTileLength, g.tileH), longs(tagTileOffsets, offsets...), longs(tagTileByteCounts, counts...))
+	} else {
+		entries = append(entries, longs(tagRowsPerStrip, g.rowsPerStrip), longs(tagStripOffsets, offsets...), longs(tagStripByteCounts, counts...))
+	}
+	if g.noData != "" {
+		entries = append(entries, ifdEntry{tagNoData, 2, len(g.noData) + 1, append([]byte(g.noData), 0)})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].tag < entries[j].tag })
+
+	// values longer than 4 bytes go before the IFD
+	values := make([]int, len(entries))
+	for i, e := range entries {
+		if len(e.data) > 4 {
+			values[i] = len(b)
+			b = append(b, e.data...)
+		}
+	}
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(b)))
+	b = append(b, byte(len(entries)), 0)
+	for i, e := range entries {
+		entry := make([]byte, 12)
+		binary.LittleEndian.PutUint16(entry, e.tag)
+		binary.LittleEndian.PutUint16(entry[2:], e.typ)
+		binary.LittleEndian.PutUint32(entry[4:], uint32(e.count))
+		if len(e.data) > 4 {
+			binary.LittleEndian.PutUint32(entry[8:], uint32(values[i]))
+		} else {
+			copy(entry[8:], e.data)
+		}
+		b = append(b, entry...)
+	}
+	b = append(b, 0, 0, 0, 0)
+	if err := ioutil.WriteFile(filepath.Join(dir, name), b, 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// elevation is a sample of a tile and the elevation we expect there, or ok false for none.
+type elevation struct {
+	lat, lon float64
+	ele      float64
+	ok       bool
+}
+
+func checkElevations(t *testing.T, d *DEM, tests []elevation) {
+	t.Helper()
+	for _, test := range tests {
+		ele, ok, err := d.Elevation(test.lat, test.lon)
+		if err != nil {
+			t.Fatalf("%v,%v: %v", test.lat, test.lon, err)
+		}
+		if ok != test.ok || math.Abs(ele-test.ele) > 1e-6 {
+			t.Errorf("%v,%v: got %v %v, want %v %v", test.lat, test.lon, ele, ok, test.ele, test.ok)
+		}
+	}
+}
+
+func TestHgt(t *testing.T) {
+	dir := t.TempDir()
+	writeHgt(t, dir, "N27E087.hgt", 3, []int16{
+		1000, 1100, 1200, // 28N
+		2000, 2100, 2200,
+		3000, 3100, -32768, // 27N
+	})
+	writeHgt(t, dir, "s01w002.HGT", 2, []int16{10, 20, 30, 40})
+	if err := ioutil.WriteFile(filepath.Join(dir, "README.txt"), []byte("not a tile"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	d, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElevations(t, d, []elevation{
+		{28, 87, 1000, true},        // north west corner
+		{27.5, 87.5, 2100, true},    // centre
+		{27.75, 87.25, 1550, true},  // between four samples
+		{28, 87.75, 1150, true},     // on the north edge
+		{27, 87, 3000, true},        // south west corner
+		{27, 88, 0, false},          // void
+		{27.25, 87.75, 0, false},    // next to a void
+		{26.9, 87.5, 0, false},      // outside
+		{-0.5, -1.5, 25, true},      // southern and western hemispheres
+		{-1, -2, 30, true},          // south west corner
+		{-0.5, -1, 30, true},        // east edge
+		{27.5, 88 + 1e-9, 0, false}, // just outside
+		{28 - 1e-12, 87 + 1e-12, 1000, true},
+	})
+
+	// tiles are read the first time they're sampled
+	writeHgt(t, dir, "N27E088.hgt", 1, []int16{0})
+	if d, err = Open(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := d.Elevation(27.5, 88.5); err == nil {
+		t.Error("expected an error for a tile of 1 sample")
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "srtm.hgt"), nil, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(dir); err == nil {
+		t.Error("expected an error for a tile named without its corner")
+	}
+	if _, err := Open(t.TempDir()); err == nil {
+		t.Error("expected an error for a directory without tiles")
+	}
+}
+
+// values returns the samples of a width x height image, each 100 per row and 10 per column, so that
+// bilinear interpolation gives exact values everywhere.
+func values(width, height int) []int16 {
+	v := make([]int16, width*height)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			v[y*width+x] = int16(100*y + 10*x)
+		}
+	}
+	return v
+}
+
+func TestGeoTiffLayouts(t *testing.T) {
+	for name, g := range map[string]geoTiff{
+		"strips":          {rowsPerStrip: 2},
+		"one strip":       {rowsPerStrip: 3},
+		"tiles":           {tileW: 2, tileH: 2},
+		"partial tiles":   {tileW: 3, tileH: 2},
+		"deflate strips":  {rowsPerStrip: 1, deflate: true},
+		"deflate tiles":   {tileW: 4, tileH: 4, deflate: true},
+		"predictor":       {rowsPerStrip: 2, deflate: true, predictor: true},
+		"predictor tiles": {tileW: 2, tileH: 2, deflate: true, predictor: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			g.width, g.height = 5, 3
+			g.values = values(5, 3)
+			g.values[0] = -1 // a negative sample
+			g.west, g.north, g.dx, g.dy = 87, 28, 0.25, 0.25
+			writeGeoTiff(t, dir, "dem.tif", g)
+			d, err := Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// pixels are areas, so samples are at pixel centres
+			checkElevations(t, d, []elevation{
+				{27.875, 87.125, -1, true},
+				{27.875, 87.375, 10, true},
+				{27.625, 87.125, 100, true},
+				{27.375, 88.125, 240, true},    // south east pixel, in the last partial tile
+				{27.5, 87.5, 165, true},        // between four samples
+				{27.625, 87.875, 130, true},    // the last column of the first tile
+				{27.26, 88.24, 240, true},      // clamped to the south east sample
+				{27.99, 87.875, 30, true},      // clamped to the top row
+				{28.01, 87.5, 0, false},        // outside
+				{27.5, 88.26, 0, false},        // outside
+				{27.25 - 1e-9, 87.5, 0, false}, // outside
+			})
+		})
+	}
+}
+
+func TestGeoTiffPixelIsPoint(t *testing.T) {
+	dir := t.TempDir()
+	writeGeoTiff(t, dir, "dem.tiff", geoTiff{
+		width: 3, height: 3, values: values(3, 3), rowsPerStrip: 3, pixelIsPoint: true,
+		west: 87, north: 28, dx: 0.5, dy: 0.5,
+	})
+	d, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the tiepoint is the north west sample, and the tile extends half a pixel beyond the samples
+	checkElevations(t, d, []elevation{
+		{28, 87, 0, true},
+		{27, 88, 220, true},
+		{27.5, 87.5, 110, true},
+		{28.2, 86.8, 0, true},
+		{26.8, 88.2, 220, true},
+		{28.3, 87, 0, false},
+	})
+}
+
+func TestGeoTiffNoData(t *testing.T) {
+	dir := t.TempDir()
+	v := values(3, 2)
+	v[5] = -9999
+	writeGeoTiff(t, dir, "dem.tif", geoTiff{
+		width: 3, height: 2, values: v, rowsPerStrip: 2, noData: "-9999",
+		west: 87, north: 28, dx: 0.5, dy: 0.5,
+	})
+	dir2 := t.TempDir()
+	writeGeoTiff(t, dir2, "dem.tif", geoTiff{
+		width: 2, height: 2, floatingPoint: true, floatingValues: []float32{1.5, float32(math.NaN()), 3.5, 4.5}, rowsPerStrip: 2,
+		west: 87, north: 28, dx: 0.5, dy: 0.5,
+	})
+	// GDAL's nodata for float32 files is the float32 minimum, written as a float64 that isn't quite it
+	dir3 := t.TempDir()
+	writeGeoTiff(t, dir3, "dem.tif", geoTiff{
+		width: 2, height: 2, floatingPoint: true, floatingValues: []float32{1.5, -math.MaxFloat32, 3.5, 4.5}, rowsPerStrip: 2,
+		noData: "-3.40282346638529e+38", west: 87, north: 28, dx: 0.5, dy: 0.5,
+	})
+	d, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElevations(t, d, []elevation{
+		{27.75, 87.25, 0, true},
+		{27.75, 87.75, 10, true},
+		{27.25, 88.25, 0, false}, // nodata
+		{27.5, 88, 0, false},     // next to nodata
+		{27.25, 87.25, 100, true},
+	})
+	d, err = Open(dir2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElevations(t, d, []elevation{
+		{27.75, 87.25, 1.5, true},
+		{27.25, 87.25, 3.5, true},
+		{27.75, 87.75, 0, false}, // NaN
+	})
+	d, err = Open(dir3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkElevations(t, d, []elevation{
+		{27.75, 87.25, 1.5, true},
+		{27.25, 87.75, 4.5, true},
+		{27.75, 87.75, 0, false}, // nodata
+	})
+}
+
+func TestGeoTiffErrors(t *testing.T) {
+	dir := t.TempDir()
+	writeGeoTiff(t, dir, "dem.tif", geoTiff{
+		width: 2, height: 2, values: values(2, 2), rowsPerStrip: 2, projected: true,
+		west: 500000, north: 3000000, dx: 30, dy: 30,
+	})
+	if _, err := Open(dir); err == nil {
+		t.Error("expected an error for a projected GeoTIFF")
+	}
+	for name, b := range map[string]string{"truncated": "II*\x00\x08", "big endian header": "MM\x00*\x00\x00\x00\xff", "not a TIFF": "GIF89a\x00\x00"} {
+		dir := t.TempDir()
+		if err := ioutil.WriteFile(filepath.Join(dir, "dem.tif"), []byte(b), 0666); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(dir); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package dem
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/tiff/lzw"
+)
+
+// TIFF tags used by openGeoTiff.
+const (
+	tagImageWidth      = 256
+	tagImageLength     = 257
+	tagBitsPerSample   = 258
+	tagCompression     = 259
+	tagStripOffsets    = 273
+	tagSamplesPerPixel = 277
+	tagRowsPerStrip    = 278
+	tagStripByteCounts = 279
+	tagPredictor       = 317
+	tagTileWidth       = 322
+	tagTileLength      = 323
+	tagTileOffsets     = 324
+	tagTileByteCounts  = 325
+	tagSampleFormat    = 339
+	tagPixelScale      = 33550
+	tagTiepoint        = 33922
+	tagGeoKeys         = 34735
+	tagNoData          = 42113
+)
+
+// openGeoTiff opens a single band GeoTIFF in latitude and longitude, e.g. from the Copernicus or
+// ASTER DEMs, or SRTM tiles converted by GDAL. Samples may be 8, 16 or 32 bit integers or 32 or 64
+// bit floats, in strips or tiles, uncompressed or with LZW or deflate compression.
+func openGeoTiff(fpath string) (*tile, error) {
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	tags, order, err := readIFD(b)
+	if err != nil {
+		return nil, err
+	}
+	number := func(tag int, def float64) float64 {
+		if v := tags[tag]; len(v.numbers) > 0 {
+			return v.numbers[0]
+		}
+		return def
+	}
+
+	width, height := int(number(tagImageWidth, 0)), int(number(tagImageLength, 0))
+	if width < 2 || height < 2 {
+		return nil, fmt.Errorf("image is %dx%d", width, height)
+	}
+	if n := number(tagSamplesPerPixel, 1); n != 1 {
+		return nil, fmt.Errorf("%v samples per pixel, DEMs have 1", n)
+	}
+	scale, tiepoint := tags[tagPixelScale].numbers, tags[tagTiepoint].numbers
+	if len(scale) < 2 || len(tiepoint) < 6 {
+		return nil, fmt.Errorf("no pixel scale and tiepoint, is it a GeoTIFF?")
+	}
+
+	// GeoKeyDirectory is a header of 4 shorts then 4 shorts for each key: id, location, count and
+	// value. Model type 2 is latitude and longitude, and raster type 2 means pixels are points
+	// rather than areas.
+	pixelIsPoint := false
+	if keys := tags[tagGeoKeys].numbers; len(keys) >= 4 {
+		for i := 4; i+3 < len(keys); i += 4 {
+			switch {
+			case keys[i] == 1024 && keys[i+1] == 0 && keys[i+3] != 2:
+				return nil, fmt.Errorf("projected DEMs are not supported, it must be in latitude and longitude")
+			case keys[i] == 1025 && keys[i+1] == 0 && keys[i+3] == 2:
+				pixelIsPoint = true
+			}
+		}
+	}
+	dx, dy := scale[0], scale[1]
+	west := tiepoint[3] - tiepoint[0]*dx
+	north := tiepoint[4] + tiepoint[1]*dy
+	if !pixelIsPoint {
+		// the tiepoint is the corner of the pixel, and we want its centre
+		west += dx / 2
+		north -= dy / 2
+	}
+
+	// the samples are float32, so nodata is compared as one: GDAL writes the float32 minimum as
+	// -3.40282346638529e+38, which isn't the same number as a float64
+	noData := float32(math.NaN())
+	if s := strings.TrimSpace(strings.TrimRight(tags[tagNoData].text, "\x00")); s != "" {
+		f, err := strconv.ParseFloat(s, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nodata value %q", s)
+		}
+		noData = float32(f)
+	}
+
+	t := &tile{
+		filename: fpath,
+		west:     west - dx/2,
+		north:    north + dy/2,
+		east:     west + (float64(width)-0.5)*dx,
+		south:    north - (float64(height)-0.5)*dy,
+	}
+	t.load = func() (*grid, error) {
+		samples, err := decodeSamples(b, tags, order, width, height)
+		if err != nil {
+			return nil, err
+		}
+		return &grid{
+			width:  width,
+			height: height,
+			west:   west,
+			north:  north,
+			dx:     dx,
+			dy:     dy,
+			value: func(x, y int) (float64, bool) {
+				v := samples[y*width+x]
+				if v == noData || math.IsNaN(float64(v)) {
+					return 0, false
+				}
+				return float64(v), true
+			},
+		}, nil
+	}
+	return t, nil
+}
+
+type tagValue struct {
+	numbers []float64
+	text    string
+}
+
+// readIFD reads the tags of the first image in a TIFF file.
+func readIFD(b []byte) (map[int]tagValue, binary.ByteOrder, error) {
+	if len(b) < 8 {
+		return nil, nil, fmt.Errorf("not a TIFF file")
+	}
+	var order binary.ByteOrder
+	switch string(b[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil, nil, fmt.Errorf("not a TIFF file")
+	}
+	if v := order.Uint16(b[2:]); v != 42 {
+		if v == 43 {
+			return nil, nil, fmt.Errorf("BigTIFF is not supported")
+		}
+		return nil, nil, fmt.Errorf("not a TIFF file")
+	}
+	offset := int(order.Uint32(b[4:]))
+	if offset+2 > len(b) {
+		return nil, nil, fmt.Errorf("truncated TIFF file")
+	}
+	count := int(order.Uint16(b[offset:]))
+	if offset+2+count*12 > len(b) {
+		return nil, nil, fmt.Errorf("truncated TIFF file")
+	}
+	sizes := map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 6: 1, 8: 2, 9: 4, 11: 4, 12: 8, 16: 8, 17: 8}
+	tags := map[int]tagValue{}
+	for i := 0; i < count; i++ {
+		entry := b[offset+2+i*12:]
+		tag, typ, n := int(order.Uint16(entry)), order.Uint16(entry[2:]), int(order.Uint32(entry[4:]))
+		size, ok := sizes[typ]
+		if !ok {
+			continue
+		}
+		data := entry[8:12]
+		if n*size > 4 {
+			start := int(order.Uint32(entry[8:]))
+			if start+n*size > len(b) {
+				return nil, nil, fmt.Errorf("truncated TIFF file")
+			}
+			data = b[start : start+n*size]
+		}
+		var v tagValue
+		if typ == 2 {
+			v.text = string(data[:n])
+		}
+		for j := 0; j < n && typ != 2; j++ {
+			d := data[j*size:]
+			var f float64
+			switch typ {
+			case 1:
+				f = float64(d[0])
+			case 6:
+				f = float64(int8(d[0]))
+			case 3:
+				f = float64(order.Uint16(d))
+			case 8:
+				f = float64(int16(order.Uint16(d)))
+			case 4:
+				f = float64(order.Uint32(d))
+			case 9:
+				f = float64(int32(order.Uint32(d)))
+			case 11:
+				f = float64(math.Float32frombits(order.Uint32(d)))
+			case 12:
+				f = math.Float64frombits(order.Uint64(d))
+			case 16:
+				f = float64(order.Uint64(d))
+			case 17:
+				f = float64(int64(order.Uint64(d)))
+			}
+			v.numbers = append(v.numbers, f)
+		}
+		tags[tag] = v
+	}
+	return tags, order, nil
+}
+
+// decodeSamples decompresses the strips or tiles of the image into one sample per pixel.
+func decodeSamples(b []byte, tags map[int]tagValue, order binary.ByteOrder, width, height int) ([]float32, error) {
+	number := func(tag int, def float64) float64 {
+		if v := tags[tag]; len(v.numbers) > 0 {
+			return v.numbers[0]
+		}
+		return def
+	}
+	bits := int(number(tagBitsPerSample, 1))
+	format := int(number(tagSampleFormat, 1))
+	compression := int(number(tagCompression, 1))
+	predictor := int(number(tagPredictor, 1))
+	if bits != 8 && bits != 16 && bits != 32 && bits != 64 || bits == 64 && format != 3 {
+		return nil, fmt.Errorf("%d bit samples are not supported", bits)
+	}
+	if predictor != 1 && predictor != 2 {
+		return nil, fmt.Errorf("predictor %d is not supported", predictor)
+	}
+	size := bits / 8
+
+	// strips are blocks as wide as the image
+	blockWidth, blockHeight := width, int(number(tagRowsPerStrip, float64(height)))
+	offsets, counts := tags[tagStripOffsets].numbers, tags[tagStripByteCounts].numbers
+	if _, tiled := tags[tagTileWidth]; tiled {
+		blockWidth, blockHeight = int(number(tagTileWidth, 0)), int(number(tagTileLength, 0))
+		offsets, counts = tags[tagTileOffsets].numbers, tags[tagTileByteCounts].numbers
+	}
+	if blockWidth < 1 || blockHeight < 1 || len(offsets) == 0 || len(offsets) != len(counts) {
+		return nil, fmt.Errorf("invalid strips or tiles")
+	}
+	across := (width + blockWidth - 1) / blockWidth
+
+	samples := make([]float32, width*height)
+	for i := range offsets {
+		start, end := int(offsets[i]), int(offsets[i])+int(counts[i])
+		if end > len(b) {
+			return nil, fmt.Errorf("truncated TIFF file")
+		}
+		var r io.Reader = bytes.NewReader(b[start:end])
+		switch compression {
+		case 1:
+		case 5:
+			r = lzw.NewReader(r, lzw.MSB, 8)
+		case 8, 32946:
+			zr, err := zlib.NewReader(r)
+			if err != nil {
+				return nil, fmt.Errorf("error decompressing block %d: %w", i, err)
+			}
+			r = zr
+		default:
+			return nil, fmt.Errorf("compression %d is not supported", compression)
+		}
+		block, err := ioutil.ReadAll(r)
+		if err != nil && len(block) < blockWidth*blockHeight*size {
+			return nil, fmt.Errorf("error decompressing block %d: %w", i, err)
+		}
+
+		x0, y0 := (i%across)*blockWidth, (i/across)*blockHeight
+		for y := 0; y < blockHeight && y0+y < height; y++ {
+			var previous float64
+			for x := 0; x < blockWidth; x++ {
+				j := (y*blockWidth + x) * size
+				if j+size > len(block) {
+					break
+				}
+				v := sampleValue(block[j:], order, bits, format)
+				if predictor == 2 && x > 0 {
+					v = wrap(v+previous, bits, format)
+				}
+				previous = v
+				if x0+x < width {
+					samples[(y0+y)*width+x0+x] = float32(v)
+				}
+			}
+		}
+	}
+	return samples, nil
+}
+
+func sampleValue(d []byte, order binary.ByteOrder, bits, format int) float64 {
+	switch {
+	case bits == 8 && format == 2:
+		return float64(int8(d[0]))
+	case bits == 8:
+		return float64(d[0])
+	case bits == 16 && format == 2:
+		return float64(int16(order.Uint16(d)))
+	case bits == 16:
+		return float64(order.Uint16(d))
+	case bits == 32 && format == 3:
+		return float64(math.Float32frombits(order.Uint32(d)))
+	case bits == 32 && format == 2:
+		return float64(int32(order.Uint32(d)))
+	case bits == 32:
+		return float64(order.Uint32(d))
+	default:
+		return math.Float64frombits(order.Uint64(d))
+	}
+}
+
+// wrap makes the sum of horizontal differencing overflow like the integer type it's stored as.
+func wrap(v float64, bits, format int) float64 {
+	switch {
+	case bits == 8 && format == 2:
+		return float64(int8(int64(v)))
+	case bits == 8:
+		return float64(uint8(int64(v)))
+	case bits == 16 && format == 2:
+		return float64(int16(int64(v)))
+	case bits == 16:
+		return float64(uint16(int64(v)))
+	case bits == 32 && format == 2:
+		return float64(int32(int64(v)))
+	case bits == 32 && format != 3:
+		return float64(uint32(int64(v)))
+	}
+	return v
+}
@@ -0,0 +1,66 @@
+package dem
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hgtName = regexp.MustCompile(`^([NS])(\d{2})([EW])(\d{3})$`)
+
+// openHgt opens an SRTM tile. The name is the south west corner, e.g. N27E087.hgt covers 27-28N and
+// 87-88E. The file is a square of big endian 16 bit samples from the north west corner, with voids
+// of -32768.
+func openHgt(fpath string) (*tile, error) {
+	name := strings.ToUpper(strings.TrimSuffix(filepath.Base(fpath), filepath.Ext(fpath)))
+	matches := hgtName.FindStringSubmatch(name)
+	if matches == nil {
+		return nil, fmt.Errorf("name should be like N27E087.hgt")
+	}
+	lat, _ := strconv.Atoi(matches[2])
+	lon, _ := strconv.Atoi(matches[4])
+	if matches[1] == "S" {
+		lat = -lat
+	}
+	if matches[3] == "W" {
+		lon = -lon
+	}
+	t := &tile{
+		filename: fpath,
+		west:     float64(lon),
+		south:    float64(lat),
+		east:     float64(lon + 1),
+		north:    float64(lat + 1),
+	}
+	t.load = func() (*grid, error) {
+		b, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return nil, err
+		}
+		size := int(math.Sqrt(float64(len(b) / 2)))
+		if size < 2 || size*size*2 != len(b) {
+			return nil, fmt.Errorf("%d bytes is not a square of 16 bit samples", len(b))
+		}
+		return &grid{
+			width:  size,
+			height: size,
+			west:   t.west,
+			north:  t.north,
+			dx:     1 / float64(size-1),
+			dy:     1 / float64(size-1),
+			value: func(x, y int) (float64, bool) {
+				v := int16(binary.BigEndian.Uint16(b[2*(y*size+x):]))
+				if v == -32768 {
+					return 0, false
+				}
+				return float64(v), true
+			},
+		}, nil
+	}
+	return t, nil
+}
@@ -0,0 +1,39 @@
+// Package geo has geographic calculations shared by the other packages.
+package geo
+
+import "math"
+
+// Distance returns the great-circle distance in km between two points given in degrees.
+func Distance(lat1 float64, lng1 float64, lat2 float64, lng2 float64) float64 {
+	const PI float64 = 3.141592653589793
+
+	radlat1 := float64(PI * lat1 / 180)
+	radlat2 := float64(PI * lat2 / 180)
+
+	theta := float64(lng1 - lng2)
+	radtheta := float64(PI * theta / 180)
+
+	dist := math.Sin(radlat1)*math.Sin(radlat2) + math.Cos(radlat1)*math.Cos(radlat2)*math.Cos(radtheta)
+
+	if dist > 1 {
+		dist = 1
+	}
+
+	dist = math.Acos(dist)
+	dist = dist * 180 / PI
+	dist = dist * 60 * 1.1515
+
+	dist = dist * 1.609344
+
+	return dist
+}
+
+// Bearing returns the initial bearing in degrees clockwise from north of the great-circle route from
+// the first point to the second.
+func Bearing(lat1, lng1, lat2, lng2 float64) float64 {
+	radlat1, radlat2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dlng := (lng2 - lng1) * math.Pi / 180
+	y := math.Sin(dlng) * math.Cos(radlat2)
+	x := math.Cos(radlat1)*math.Sin(radlat2) - math.Sin(radlat1)*math.Cos(radlat2)*math.Cos(dlng)
+	return math.Mod(math.Atan2(y, x)*180/math.Pi+360, 360)
+}
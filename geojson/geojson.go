@@ -0,0 +1,78 @@
+// Package geojson writes GeoJSON files (RFC 7946) for web maps such as Leaflet and MapLibre.
+package geojson
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/dave/ght/gpx"
+)
+
+// FeatureCollection is the root of a GeoJSON file.
+type FeatureCollection struct {
+	Type     string     `json:"type"` // always "FeatureCollection"
+	Features []*Feature `json:"features"`
+}
+
+// Feature is a geometry with properties.
+type Feature struct {
+	Type       string                 `json:"type"` // always "Feature"
+	Geometry   Geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// Geometry is a Point, with Coordinates a single position, or a LineString, with Coordinates a list
+// of positions. Positions are [lon, lat, ele].
+type Geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// New returns an empty feature collection.
+func New() *FeatureCollection {
+	return &FeatureCollection{Type: "FeatureCollection", Features: []*Feature{}}
+}
+
+// AddPoint adds a Point feature.
+func (c *FeatureCollection) AddPoint(p gpx.Point, properties map[string]interface{}) {
+	c.Features = append(c.Features, &Feature{
+		Type:       "Feature",
+		Geometry:   Geometry{Type: "Point", Coordinates: position(p)},
+		Properties: properties,
+	})
+}
+
+// AddLineString adds a LineString feature.
+func (c *FeatureCollection) AddLineString(points []gpx.Point, properties map[string]interface{}) {
+	coordinates := make([][]float64, len(points))
+	for i, p := range points {
+		coordinates[i] = position(p)
+	}
+	c.Features = append(c.Features, &Feature{
+		Type:       "Feature",
+		Geometry:   Geometry{Type: "LineString", Coordinates: coordinates},
+		Properties: properties,
+	})
+}
+
+func position(p gpx.Point) []float64 {
+	return []float64{p.Lon, p.Lat, p.Ele}
+}
+
+// Marshal encodes a GeoJSON file.
+func Marshal(c *FeatureCollection) ([]byte, error) {
+	return json.MarshalIndent(c, "", "  ")
+}
+
+// Save writes a GeoJSON file.
+func Save(c *FeatureCollection, filename string) error {
+	b, err := Marshal(c)
+	if err != nil {
+		return fmt.Errorf("error encoding geojson for %q: %w", filename, err)
+	}
+	if err := ioutil.WriteFile(filename, b, 0777); err != nil {
+		return fmt.Errorf("error writing file %q: %w", filename, err)
+	}
+	return nil
+}
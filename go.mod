@@ -18,6 +18,7 @@ require (
 	golang.org/x/net v0.42.0
 	golang.org/x/oauth2 v0.30.0
 	google.golang.org/api v0.241.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,309 @@
+// Package gpx reads and writes GPX 1.1 files.
+package gpx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/dave/ght/geo"
+)
+
+// Namespace is the GPX 1.1 namespace, set as Xmlns on files we create.
+const Namespace = "http://www.topografix.com/GPX/1/1"
+
+// GPX is a GPX 1.1 file (https://www.topografix.com/GPX/1/1/). Everything in the schema is kept, and
+// extensions are kept as raw XML, so files edited in gpx.studio or Garmin tools survive a round trip.
+// Fields are in schema order because encoding/xml writes them in that order.
+type GPX struct {
+	XMLName    xml.Name    `xml:"gpx"`
+	Xmlns      string      `xml:"xmlns,attr,omitempty"`
+	Version    string      `xml:"version,attr"`
+	Creator    string      `xml:"creator,attr,omitempty"`
+	Attrs      []xml.Attr  `xml:",any,attr"` // other namespace declarations, xsi:schemaLocation etc.
+	Metadata   *Metadata   `xml:"metadata,omitempty"`
+	Waypoints  []Waypoint  `xml:"wpt"`
+	Routes     []Route     `xml:"rte"`
+	Tracks     []Track     `xml:"trk"`
+	Extensions *Extensions `xml:"extensions,omitempty"`
+}
+
+func (g *GPX) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	type plain GPX
+	if err := d.DecodeElement((*plain)(g), &start); err != nil {
+		return err
+	}
+	// the decoded name has the namespace, which would be written as a second xmlns attribute
+	g.XMLName = xml.Name{}
+	g.Attrs = prefixAttrs(g.Attrs)
+	return nil
+}
+
+// prefixAttrs turns the namespaced attributes the decoder gives us back into their prefixed form
+// (e.g. xmlns:gpxx="..." and xsi:schemaLocation="..."). encoding/xml can't write namespaced
+// attributes itself, and the raw XML in extensions refers to these prefixes.
+func prefixAttrs(attrs []xml.Attr) []xml.Attr {
+	prefixes := map[string]string{}
+	for _, a := range attrs {
+		if a.Name.Space == "xmlns" {
+			prefixes[a.Value] = a.Name.Local
+		}
+	}
+	var out []xml.Attr
+	for _, a := range attrs {
+		switch {
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			// default namespace is in Xmlns
+			continue
+		case a.Name.Space == "xmlns":
+			a.Name = xml.Name{Local: "xmlns:" + a.Name.Local}
+		case a.Name.Space != "":
+			prefix, ok := prefixes[a.Name.Space]
+			if !ok {
+				continue
+			}
+			a.Name = xml.Name{Local: prefix + ":" + a.Name.Local}
+		}
+		out = append(out, a)
+	}
+	return out
+}
+
+// Metadata describes the file.
+type Metadata struct {
+	Name       string      `xml:"name,omitempty"`
+	Desc       string      `xml:"desc,omitempty"`
+	Author     *Person     `xml:"author,omitempty"`
+	Copyright  *Copyright  `xml:"copyright,omitempty"`
+	Links      []Link      `xml:"link"`
+	Time       *time.Time  `xml:"time,omitempty"`
+	Keywords   string      `xml:"keywords,omitempty"`
+	Bounds     *Bounds     `xml:"bounds,omitempty"`
+	Extensions *Extensions `xml:"extensions,omitempty"`
+}
+
+// Person is a person or organisation.
+type Person struct {
+	Name  string `xml:"name,omitempty"`
+	Email *Email `xml:"email,omitempty"`
+	Link  *Link  `xml:"link,omitempty"`
+}
+
+// Email is an email address split into id and domain.
+type Email struct {
+	ID     string `xml:"id,attr"`
+	Domain string `xml:"domain,attr"`
+}
+
+// Copyright is the copyright holder and license of the file.
+type Copyright struct {
+	Author  string `xml:"author,attr"`
+	Year    string `xml:"year,omitempty"`
+	License string `xml:"license,omitempty"`
+}
+
+// Link is a link to an external resource.
+type Link struct {
+	Href string `xml:"href,attr"`
+	Text string `xml:"text,omitempty"`
+	Type string `xml:"type,omitempty"`
+}
+
+// Bounds is the extent of the file.
+type Bounds struct {
+	MinLat float64 `xml:"minlat,attr"`
+	MinLon float64 `xml:"minlon,attr"`
+	MaxLat float64 `xml:"maxlat,attr"`
+	MaxLon float64 `xml:"maxlon,attr"`
+}
+
+// Extensions holds the contents of an extensions element as raw XML, so extensions we don't know
+// about are written back unchanged.
+type Extensions struct {
+	XML string `xml:",innerxml"`
+}
+
+// Waypoint is a wptType, used for waypoints, route points and track points.
+type Waypoint struct {
+	Point
+	Time          *time.Time  `xml:"time,omitempty"`
+	MagVar        *float64    `xml:"magvar,omitempty"`
+	GeoidHeight   *float64    `xml:"geoidheight,omitempty"`
+	Name          string      `xml:"name,omitempty"`
+	Cmt           string      `xml:"cmt,omitempty"`
+	Desc          string      `xml:"desc,omitempty"`
+	Src           string      `xml:"src,omitempty"`
+	Links         []Link      `xml:"link"`
+	Sym           string      `xml:"sym,omitempty"`
+	Type          string      `xml:"type,omitempty"`
+	Fix           string      `xml:"fix,omitempty"`
+	Sat           *int        `xml:"sat,omitempty"`
+	HDOP          *float64    `xml:"hdop,omitempty"`
+	VDOP          *float64    `xml:"vdop,omitempty"`
+	PDOP          *float64    `xml:"pdop,omitempty"`
+	AgeOfDGPSData *float64    `xml:"ageofdgpsdata,omitempty"`
+	DGPSID        *int        `xml:"dgpsid,omitempty"`
+	Extensions    *Extensions `xml:"extensions,omitempty"`
+}
+
+type plainWaypoint Waypoint
+
+// waypointXML is a waypoint with its elevation, which is first in the schema, left out if the point
+// has none. A real elevation of 0 is written.
+type waypointXML struct {
+	Ele *float64 `xml:"ele,omitempty"`
+	plainWaypoint
+}
+
+func (w Waypoint) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	x := waypointXML{plainWaypoint: plainWaypoint(w)}
+	if !w.NoEle {
+		ele := w.Ele
+		x.Ele = &ele
+	}
+	return e.EncodeElement(x, start)
+}
+
+func (w *Waypoint) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var x waypointXML
+	if err := d.DecodeElement(&x, &start); err != nil {
+		return err
+	}
+	*w = Waypoint(x.plainWaypoint)
+	if x.Ele != nil {
+		w.Ele = *x.Ele
+	} else {
+		w.NoEle = true
+	}
+	return nil
+}
+
+// Route is an ordered list of route points leading to a destination.
+type Route struct {
+	Name       string      `xml:"name,omitempty"`
+	Cmt        string      `xml:"cmt,omitempty"`
+	Desc       string      `xml:"desc,omitempty"`
+	Src        string      `xml:"src,omitempty"`
+	Links      []Link      `xml:"link"`
+	Number     *int        `xml:"number,omitempty"`
+	Type       string      `xml:"type,omitempty"`
+	Extensions *Extensions `xml:"extensions,omitempty"`
+	Points     []Waypoint  `xml:"rtept"`
+}
+
+// Point is a location and elevation, the part of a waypoint used for calculations.
+type Point struct {
+	Lat   float64 `xml:"lat,attr"`
+	Lon   float64 `xml:"lon,attr"`
+	Ele   float64 `xml:"-"` // written by Waypoint.MarshalXML
+	NoEle bool    `xml:"-"` // the point has no elevation (Ele is 0), so none is written
+}
+
+// Track is an ordered list of points describing a path, in one or more segments.
+type Track struct {
+	Name       string         `xml:"name,omitempty"`
+	Cmt        string         `xml:"cmt,omitempty"`
+	Desc       string         `xml:"desc,omitempty"`
+	Src        string         `xml:"src,omitempty"`
+	Links      []Link         `xml:"link"`
+	Number     *int           `xml:"number,omitempty"`
+	Type       string         `xml:"type,omitempty"`
+	Extensions *Extensions    `xml:"extensions,omitempty"`
+	Segments   []TrackSegment `xml:"trkseg"`
+}
+
+// TrackSegment is a continuous span of track points.
+type TrackSegment struct {
+	Points     []Waypoint  `xml:"trkpt"`
+	Extensions *Extensions `xml:"extensions,omitempty"`
+}
+
+// Marshal encodes a GPX file.
+func Marshal(g GPX) ([]byte, error) {
+	bw, err := xml.MarshalIndent(g, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(xml.Header + string(bw)), nil
+}
+
+// Save writes a GPX file.
+func Save(g GPX, filename string) error {
+	b, err := Marshal(g)
+	if err != nil {
+		return fmt.Errorf("error encoding xml for %q: %w", filename, err)
+	}
+	if err := ioutil.WriteFile(filename, b, 0777); err != nil {
+		return fmt.Errorf("error writing file %q: %w", filename, err)
+	}
+	return nil
+}
+
+// Load reads a GPX file.
+func Load(filename string) (GPX, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return GPX{}, fmt.Errorf("error reading file %q: %w", filename, err)
+	}
+	var g GPX
+	if err := xml.NewDecoder(bytes.NewBuffer(b)).Decode(&g); err != nil {
+		return GPX{}, fmt.Errorf("error decoding xml for %q: %w", filename, err)
+	}
+	return g, nil
+}
+
+// RoutePoints returns the points of all the routes in the file, or if there are no routes, the points
+// of all the segments of all the tracks (gpx.studio converts all routes to tracks, so we must handle
+// some input files with tracks).
+func (g GPX) RoutePoints() ([]Waypoint, error) {
+	var pts []Waypoint
+	if len(g.Routes) > 0 {
+		for _, r := range g.Routes {
+			pts = append(pts, r.Points...)
+		}
+	} else {
+		for _, t := range g.Tracks {
+			for _, s := range t.Segments {
+				pts = append(pts, s.Points...)
+			}
+		}
+	}
+	if len(pts) == 0 {
+		return nil, fmt.Errorf("no route or track points")
+	}
+	return pts, nil
+}
+
+// Points returns the locations of the route or track points, see RoutePoints.
+func (g GPX) Points() ([]Point, error) {
+	wpts, err := g.RoutePoints()
+	if err != nil {
+		return nil, err
+	}
+	return Locations(wpts), nil
+}
+
+// Locations returns the location of each waypoint.
+func Locations(wpts []Waypoint) []Point {
+	pts := make([]Point, len(wpts))
+	for i, w := range wpts {
+		pts[i] = w.Point
+	}
+	return pts
+}
+
+// Closest returns the index of the point closest to p.
+func Closest(points []Point, p Point) int {
+	minDist := -1.0
+	minIndex := 0
+	for k, v := range points {
+		d := geo.Distance(v.Lat, v.Lon, p.Lat, p.Lon)
+		if d < minDist || minDist == -1.0 {
+			minDist = d
+			minIndex = k
+		}
+	}
+	return minIndex
+}
@@ -0,0 +1,162 @@
+package gpx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// garmin is a file in the style of Garmin BaseCamp, with namespaced extensions, a schema location and
+// a route point at sea level.
+const garmin = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx xmlns="http://www.topografix.com/GPX/1/1" xmlns:gpxx="http://www.garmin.com/xmlschemas/GpxExtensions/v3" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd" version="1.1" creator="Garmin Desktop App">
+	<metadata>
+		<name>L001</name>
+		<time>2019-10-01T06:30:00Z</time>
+		<bounds minlat="27.35" minlon="87.67" maxlat="27.4" maxlon="87.7"></bounds>
+	</metadata>
+	<wpt lat="27.35" lon="87.67">
+		<ele>1820</ele>
+		<name>L001 Taplejung</name>
+		<sym>Flag, Blue</sym>
+		<extensions><gpxx:WaypointExtension><gpxx:DisplayMode>SymbolAndName</gpxx:DisplayMode></gpxx:WaypointExtension></extensions>
+	</wpt>
+	<rte>
+		<name>L001 Taplejung to Mitlung</name>
+		<extensions><gpxx:RouteExtension><gpxx:DisplayColor>Magenta</gpxx:DisplayColor></gpxx:RouteExtension></extensions>
+		<rtept lat="27.35" lon="87.67"><ele>1820</ele></rtept>
+		<rtept lat="27.37" lon="87.68"><ele>0</ele></rtept>
+		<rtept lat="27.4" lon="87.7"><ele>921.5</ele></rtept>
+	</rte>
+</gpx>`
+
+// studio is a file in the style of gpx.studio, which writes tracks with several segments, and points
+// without an elevation where it has none.
+const studio = `<?xml version="1.0" encoding="UTF-8"?>
+<gpx xmlns="http://www.topografix.com/GPX/1/1" version="1.1" creator="https://gpx.studio">
+	<trk>
+		<name>L002</name>
+		<trkseg>
+			<trkpt lat="27.4" lon="87.7"><ele>921.5</ele></trkpt>
+			<trkpt lat="27.41" lon="87.71"></trkpt>
+		</trkseg>
+		<trkseg>
+			<trkpt lat="27.42" lon="87.72"><ele>990</ele></trkpt>
+		</trkseg>
+	</trk>
+</gpx>`
+
+func load(t *testing.T, contents string) GPX {
+	t.Helper()
+	fpath := filepath.Join(t.TempDir(), "in.gpx")
+	if err := ioutil.WriteFile(fpath, []byte(contents), 0666); err != nil {
+		t.Fatal(err)
+	}
+	g, err := Load(fpath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name, contents := range map[string]string{"garmin": garmin, "studio": studio} {
+		t.Run(name, func(t *testing.T) {
+			g := load(t, contents)
+			b, err := Marshal(g)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), "\n<gpx ") || !strings.HasSuffix(string(b), "</gpx>") {
+				t.Fatalf("root element is not gpx:\n%s", b)
+			}
+			if n := strings.Count(string(b), `xmlns="`); n != 1 {
+				t.Errorf("found %d default namespace declarations, want 1:\n%s", n, b)
+			}
+			if name == "studio" && !strings.Contains(string(b), `<trkpt lat="27.41" lon="87.71"></trkpt>`) {
+				t.Errorf("point without an elevation was given one:\n%s", b)
+			}
+			again := load(t, string(b))
+			if !reflect.DeepEqual(g, again) {
+				t.Errorf("round trip changed the file:\n%#v\n%#v", g, again)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	g := load(t, garmin)
+	if g.Version != "1.1" || g.Creator != "Garmin Desktop App" {
+		t.Errorf("got version %q creator %q", g.Version, g.Creator)
+	}
+	if len(g.Waypoints) != 1 || g.Waypoints[0].Name != "L001 Taplejung" || g.Waypoints[0].Sym != "Flag, Blue" {
+		t.Errorf("got waypoints %#v", g.Waypoints)
+	}
+	if e := g.Waypoints[0].Extensions; e == nil || !strings.Contains(e.XML, "<gpxx:DisplayMode>SymbolAndName</gpxx:DisplayMode>") {
+		t.Errorf("waypoint extensions not kept: %#v", e)
+	}
+	var attrs []string
+	for _, a := range g.Attrs {
+		attrs = append(attrs, a.Name.Local)
+	}
+	if want := []string{"xmlns:gpxx", "xmlns:xsi", "xsi:schemaLocation"}; !reflect.DeepEqual(attrs, want) {
+		t.Errorf("got attributes %v, want %v", attrs, want)
+	}
+	points, err := g.Points()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Point{{Lat: 27.35, Lon: 87.67, Ele: 1820}, {Lat: 27.37, Lon: 87.68, Ele: 0}, {Lat: 27.4, Lon: 87.7, Ele: 921.5}}; !reflect.DeepEqual(points, want) {
+		t.Errorf("got points %v, want %v", points, want)
+	}
+
+	points, err = load(t, studio).Points()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 {
+		t.Fatalf("got %d track points, want the 3 of both segments", len(points))
+	}
+	if !points[1].NoEle || points[0].NoEle {
+		t.Errorf("got points %v, want only the second without an elevation", points)
+	}
+}
+
+func TestMarshalNew(t *testing.T) {
+	g := GPX{
+		Xmlns:     Namespace,
+		Version:   "1.1",
+		Waypoints: []Waypoint{{Point: Point{Lat: 27.37, Lon: 87.68}, Name: "L001 Sea level"}},
+	}
+	b, err := Marshal(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if start.Name != (xml.Name{Space: Namespace, Local: "gpx"}) {
+				t.Errorf("got root element %v", start.Name)
+			}
+			break
+		}
+	}
+	if !strings.Contains(string(b), "<ele>0</ele>") {
+		t.Errorf("elevation of 0 not written:\n%s", b)
+	}
+}
+
+func TestClosest(t *testing.T) {
+	points := []Point{{Lat: 27.35, Lon: 87.67}, {Lat: 27.37, Lon: 87.68}, {Lat: 27.4, Lon: 87.7}}
+	if i := Closest(points, Point{Lat: 27.369, Lon: 87.681}); i != 1 {
+		t.Errorf("got %d, want 1", i)
+	}
+}
@@ -0,0 +1,360 @@
+// Package kml reads and writes KML files for Google Earth and maps apps.
+package kml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/dave/ght/gpx"
+)
+
+/*
+<?xml version="1.0" encoding="UTF-8"?>
+<kml>
+	<Document>
+		<name>Great Himalaya Trail</name>
+        <description>...</description>
+        <visibility>1</visibility>
+        <open>1</open>
+        <Style id="route_red">
+            <LineStyle>
+            <color>961400FF</color>
+            <width>4</width>
+            </LineStyle>
+        </Style>
+        ...
+
+        <Folder>
+            <name>Waypoints</name>
+            <description>...</description>
+            <visibility>1</visibility>
+            <open>0</open>
+
+            <Placemark>
+                <name>...</name>
+                <visibility>1</visibility>
+                <open>0</open>
+                <description>...</description>
+                <Point>
+                    <coordinates>
+                        lat,lon,ele
+                    </coordinates>
+                </Point>
+            </Placemark>
+            ...
+		</Folder>
+
+		<Folder>
+            <name>Routes</name>
+            <description>...</description>
+            <visibility>1</visibility>
+            <open>0</open>
+
+			<Placemark>
+                <visibility>0</visibility>
+                <open>0</open>
+                <styleUrl>#route_red</styleUrl>
+                <name>...</name>
+                <description>...</description>
+                <LineString>
+                    <extrude>true</extrude>
+                    <tessellate>true</tessellate>
+                    <altitudeMode>clampToGround</altitudeMode>
+                    <coordinates>
+                        lat,lon,ele lat,lon,ele lat,lon,ele
+                    </coordinates>
+                </LineString>
+            </Placemark>
+            ...
+
+        </Folder>
+	</Document>
+</kml>
+*/
+
+// PointToCoordinates formats a point as KML coordinates (lon,lat,ele).
+func PointToCoordinates(point gpx.Point) string {
+	return fmt.Sprintf("%v,%v,%v", point.Lon, point.Lat, point.Ele)
+}
+
+// PointsToCoordinates formats a list of points as KML coordinates.
+func PointsToCoordinates(points []gpx.Point) string {
+	w := strings.Builder{}
+	for i, point := range points {
+		if i > 0 {
+			w.WriteString(" ")
+		}
+		w.WriteString(fmt.Sprintf("%v,%v,%v", point.Lon, point.Lat, point.Ele))
+	}
+	return w.String()
+}
+
+// Colors are the line colours of the routes, as KML aabbggrr. FromSections adds a style for each,
+// with the colour's name as its id.
+var Colors = []struct{ Name, Color string }{
+	{"red", "961400FF"},
+	{"green", "9678FF00"},
+	{"blue", "96FF7800"},
+	{"cyan", "96F0FF14"},
+	{"orange", "961478FF"},
+	{"dark_green", "96008C14"},
+	{"purple", "96FF7878"},
+	{"pink", "96A078F0"},
+	{"brown", "96143C96"},
+	{"dark_blue", "96F01414"},
+}
+
+// FromGpx converts the waypoints and routes in a GPX file to a KML document called name.
+func FromGpx(g gpx.GPX, name string) KML {
+	return FromSections(name, []Section{{Waypoints: g.Waypoints, Routes: g.Routes}}, nil)
+}
+
+// Section is a group of waypoints and routes, e.g. the legs of a region of the trail.
+type Section struct {
+	Name, Description string
+	Waypoints         []gpx.Waypoint
+	Routes            []gpx.Route
+}
+
+// Styling is the shared styles of a document, and which of them each route and waypoint uses by
+// style id, so placemarks refer to a style by styleUrl rather than each having a copy of it.
+type Styling struct {
+	Styles   []*Style                    // added to the document after the colour styles
+	Balloon  string                      // BalloonStyle text of the colour styles, if any
+	Route    func(r gpx.Route) string    // style of a route, e.g. "red"
+	Waypoint func(w gpx.Waypoint) string // style of a waypoint, or "" for none
+	Data     func(r gpx.Route) []Data    // extended data of a route, e.g. stats for the balloon
+}
+
+// FromSections converts sections of waypoints and routes to a KML document called name. Each section
+// with a name gets a folder of its own, and the waypoints and routes of a section without a name go
+// at the top level. Empty sections are left out. If styling is nil, every route is green.
+func FromSections(name string, sections []Section, styling *Styling) KML {
+	if styling == nil {
+		styling = &Styling{}
+	}
+
+	var styles []*Style
+	for _, c := range Colors {
+		style := &Style{
+			Id: c.Name,
+			LineStyle: &LineStyle{
+				Color: c.Color,
+				Width: 4,
+			},
+		}
+		if styling.Balloon != "" {
+			style.BalloonStyle = &BalloonStyle{Text: styling.Balloon}
+		}
+		styles = append(styles, style)
+	}
+	styles = append(styles, styling.Styles...)
+
+	var folders []*Folder
+	for _, section := range sections {
+		if len(section.Waypoints) == 0 && len(section.Routes) == 0 {
+			continue
+		}
+		if section.Name == "" {
+			folders = append(folders, sectionFolders(section, styling)...)
+			continue
+		}
+		folders = append(folders, &Folder{
+			Name:        section.Name,
+			Description: section.Description,
+			Visibility:  1,
+			Open:        0,
+			Folders:     sectionFolders(section, styling),
+		})
+	}
+
+	k := KML{
+		Xmlns: "http://www.opengis.net/kml/2.2",
+		Document: Document{
+			Name:        name,
+			Description: "",
+			Visibility:  1,
+			Open:        1,
+			Styles:      styles,
+			Folders:     folders,
+		},
+	}
+	return k
+}
+
+// sectionFolders returns the Waypoints and Routes folders of a section.
+func sectionFolders(section Section, styling *Styling) []*Folder {
+	var folders []*Folder
+	if len(section.Waypoints) > 0 {
+		waypointFolder := &Folder{
+			Name:        "Waypoints",
+			Description: "",
+			Visibility:  1,
+			Open:        0,
+		}
+		for _, w := range section.Waypoints {
+			placemark := &Placemark{
+				Name:        w.Name,
+				Description: w.Desc,
+				Visibility:  1,
+				Open:        0,
+
+				Point: &Point{
+					Coordinates: PointToCoordinates(w.Point),
+				},
+			}
+			if styling.Waypoint != nil {
+				if id := styling.Waypoint(w); id != "" {
+					placemark.StyleUrl = "#" + id
+				}
+			}
+			waypointFolder.Placemarks = append(waypointFolder.Placemarks, placemark)
+		}
+		folders = append(folders, waypointFolder)
+	}
+	if len(section.Routes) > 0 {
+		routesFolder := &Folder{
+			Name:        "Routes",
+			Description: "",
+			Visibility:  1,
+			Open:        0,
+		}
+		for _, r := range section.Routes {
+			placemark := &Placemark{
+				Name:        r.Name,
+				Description: r.Desc,
+				Visibility:  0,
+				Open:        0,
+				StyleUrl:    "#green",
+				LineString: &LineString{
+					Extrude:      true,
+					Tessellate:   true,
+					AltitudeMode: "clampToGround",
+					Coordinates:  PointsToCoordinates(gpx.Locations(r.Points)),
+				},
+			}
+			if styling.Route != nil {
+				placemark.StyleUrl = "#" + styling.Route(r)
+			}
+			if styling.Data != nil {
+				if data := styling.Data(r); len(data) > 0 {
+					placemark.ExtendedData = &ExtendedData{Data: data}
+				}
+			}
+			routesFolder.Placemarks = append(routesFolder.Placemarks, placemark)
+		}
+		folders = append(folders, routesFolder)
+	}
+	return folders
+}
+
+// Marshal encodes a KML file.
+func Marshal(k KML) ([]byte, error) {
+	bw, err := xml.MarshalIndent(k, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return []byte(xml.Header + string(bw)), nil
+}
+
+// Save writes a KML file.
+func Save(k KML, filename string) error {
+	b, err := Marshal(k)
+	if err != nil {
+		return fmt.Errorf("error encoding xml for %q: %w", filename, err)
+	}
+	if err := ioutil.WriteFile(filename, b, 0777); err != nil {
+		return fmt.Errorf("error writing file %q: %w", filename, err)
+	}
+	return nil
+}
+
+// KML is the root of a KML file.
+type KML struct {
+	XMLName  xml.Name `xml:"kml"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	XmlnsGx  string   `xml:"xmlns:gx,attr,omitempty"` // GxNamespace, if the document has tours
+	Document Document `xml:"Document"`
+}
+
+type Document struct {
+	Name        string    `xml:"name"`
+	Description string    `xml:"description"`
+	Visibility  int       `xml:"visibility"`
+	Open        int       `xml:"open"`
+	Styles      []*Style  `xml:"Style"`
+	Folders     []*Folder `xml:"Folder"`
+	Tours       []*Tour   `xml:"gx:Tour"`
+}
+
+type Style struct {
+	Id           string        `xml:"id,attr,omitempty"`
+	IconStyle    *IconStyle    `xml:"IconStyle,omitempty"`
+	LineStyle    *LineStyle    `xml:"LineStyle,omitempty"`
+	BalloonStyle *BalloonStyle `xml:"BalloonStyle,omitempty"`
+}
+
+type IconStyle struct {
+	Scale float64 `xml:"scale,omitempty"`
+	Icon  Icon    `xml:"Icon"`
+}
+
+type Icon struct {
+	Href string `xml:"href"`
+}
+
+// BalloonStyle is the template of the balloon shown when a placemark is clicked, with entities such
+// as $[name], $[description] and $[length] for the placemark's extended data.
+type BalloonStyle struct {
+	Text string `xml:"text"`
+}
+
+type LineStyle struct {
+	Color string `xml:"color"`
+	Width int    `xml:"width,omitempty"`
+}
+
+type Folder struct {
+	Name        string       `xml:"name"`
+	Description string       `xml:"description"`
+	Visibility  int          `xml:"visibility"`
+	Open        int          `xml:"open"`
+	Folders     []*Folder    `xml:"Folder"`
+	Placemarks  []*Placemark `xml:"Placemark"`
+}
+
+type Placemark struct {
+	Id           string        `xml:"id,attr,omitempty"`
+	Name         string        `xml:"name"`
+	Description  string        `xml:"description"`
+	Visibility   int           `xml:"visibility"`
+	Open         int           `xml:"open"`
+	StyleUrl     string        `xml:"styleUrl,omitempty"`
+	ExtendedData *ExtendedData `xml:"ExtendedData,omitempty"`
+	Point        *Point        `xml:"Point,omitempty"`
+	LineString   *LineString   `xml:"LineString,omitempty"`
+	Style        *Style        `xml:"Style"`
+}
+
+type ExtendedData struct {
+	Data []Data `xml:"Data"`
+}
+
+// Data is a named value of a placemark, shown in its balloon by $[name].
+type Data struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type Point struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+type LineString struct {
+	Extrude      bool   `xml:"extrude"`
+	Tessellate   bool   `xml:"tessellate"`
+	AltitudeMode string `xml:"altitudeMode"`
+	Coordinates  string `xml:"coordinates"`
+}
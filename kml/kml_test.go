@@ -0,0 +1,127 @@
+package kml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"reflect"
+	"testing"
+
+	"github.com/dave/ght/gpx"
+)
+
+func testGpx() gpx.GPX {
+	return gpx.GPX{
+		Waypoints: []gpx.Waypoint{
+			{Point: gpx.Point{Lat: 27.35, Lon: 87.67, Ele: 1820}, Name: "L001 Taplejung", Desc: "Bus park"},
+		},
+		Routes: []gpx.Route{{
+			Name: "L001 Taplejung to Mitlung",
+			Points: []gpx.Waypoint{
+				{Point: gpx.Point{Lat: 27.35, Lon: 87.67, Ele: 1820}},
+				{Point: gpx.Point{Lat: 27.37, Lon: 87.68, Ele: 0}},
+				{Point: gpx.Point{Lat: 27.4, Lon: 87.7, Ele: 921.5}},
+			},
+		}},
+	}
+}
+
+// root returns the name of the root element of an XML file.
+func root(t *testing.T, b []byte) xml.Name {
+	t.Helper()
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	b, err := Marshal(FromGpx(testGpx(), "Great Himalaya Trail"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := root(t, b); name != (xml.Name{Space: "http://www.opengis.net/kml/2.2", Local: "kml"}) {
+		t.Errorf("got root element %v", name)
+	}
+
+	// what we write is what we read
+	g, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testGpx()
+	if len(g.Waypoints) != 1 || g.Waypoints[0].Name != want.Waypoints[0].Name || g.Waypoints[0].Point != want.Waypoints[0].Point {
+		t.Errorf("got waypoints %#v", g.Waypoints)
+	}
+	if len(g.Routes) != 1 || g.Routes[0].Name != want.Routes[0].Name {
+		t.Fatalf("got routes %#v", g.Routes)
+	}
+	if got := gpx.Locations(g.Routes[0].Points); !reflect.DeepEqual(got, gpx.Locations(want.Routes[0].Points)) {
+		t.Errorf("got route points %v", got)
+	}
+}
+
+func TestMarshalTour(t *testing.T) {
+	k := FromGpx(testGpx(), "Tours")
+	k.XmlnsGx = GxNamespace
+	k.Document.Tours = []*Tour{{Name: "L001", Playlist: Playlist{Steps: []interface{}{
+		&FlyTo{Duration: 5, Mode: "bounce", LookAt: LookAt{Longitude: 87.67, Latitude: 27.35, Range: 1000}},
+		ShowBalloon("stop-1", true),
+		&Wait{Duration: 3},
+	}}}}
+	b, err := Marshal(k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := root(t, b); name.Local != "kml" {
+		t.Errorf("got root element %v", name)
+	}
+
+	// the gx: elements must resolve to the extensions namespace
+	var steps []xml.Name
+	d := xml.NewDecoder(bytes.NewReader(b))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			break
+		}
+		if start, ok := tok.(xml.StartElement); ok && start.Name.Space == GxNamespace {
+			steps = append(steps, start.Name)
+		}
+	}
+	if len(steps) == 0 || steps[0].Local != "Tour" {
+		t.Errorf("got gx elements %v", steps)
+	}
+}
+
+func TestMarshalKMZ(t *testing.T) {
+	b, err := MarshalKMZ(FromGpx(testGpx(), "Great Himalaya Trail"), map[string][]byte{"images/L001.jpg": {0xff, 0xd8}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := unzipKML(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := root(t, doc); name.Local != "kml" {
+		t.Errorf("got root element %v", name)
+	}
+}
+
+func TestParseCoordinates(t *testing.T) {
+	points, err := ParseCoordinates("\n\t87.67,27.35,1820 87.68,27.37\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []gpx.Point{{Lat: 27.35, Lon: 87.67, Ele: 1820}, {Lat: 27.37, Lon: 87.68, NoEle: true}}; !reflect.DeepEqual(points, want) {
+		t.Errorf("got %v, want %v", points, want)
+	}
+	if _, err := ParseCoordinates("87.67"); err == nil {
+		t.Error("expected an error for a coordinate without a latitude")
+	}
+}
@@ -0,0 +1,60 @@
+package kml
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// MarshalKMZ encodes a KMZ file: a zip of the KML document as doc.kml and the files it refers to,
+// e.g. images/L001.jpg, by their paths in the zip.
+func MarshalKMZ(k KML, files map[string][]byte) ([]byte, error) {
+	doc, err := Marshal(k)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	// Google Earth reads the first .kml file in the zip, so doc.kml goes first
+	add := func(name string, b []byte, method uint16) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	if err := add("doc.kml", doc, zip.Deflate); err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		// images are already compressed
+		if err := add(name, files[name], zip.Store); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SaveKMZ writes a KMZ file.
+func SaveKMZ(k KML, files map[string][]byte, filename string) error {
+	b, err := MarshalKMZ(k, files)
+	if err != nil {
+		return fmt.Errorf("error encoding kmz for %q: %w", filename, err)
+	}
+	if err := ioutil.WriteFile(filename, b, 0777); err != nil {
+		return fmt.Errorf("error writing file %q: %w", filename, err)
+	}
+	return nil
+}
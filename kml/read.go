@@ -0,0 +1,167 @@
+package kml
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/dave/ght/gpx"
+)
+
+// Load reads the routes and waypoints of a KML or KMZ file, e.g. one edited in Google Earth.
+func Load(filename string) (gpx.GPX, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return gpx.GPX{}, fmt.Errorf("error reading file %q: %w", filename, err)
+	}
+	if bytes.HasPrefix(b, []byte("PK")) {
+		if b, err = unzipKML(b); err != nil {
+			return gpx.GPX{}, fmt.Errorf("error reading kmz %q: %w", filename, err)
+		}
+	}
+	g, err := Decode(bytes.NewReader(b))
+	if err != nil {
+		return gpx.GPX{}, fmt.Errorf("error decoding kml %q: %w", filename, err)
+	}
+	return g, nil
+}
+
+// unzipKML returns the KML document of a KMZ file: doc.kml, or else the first .kml file in the zip.
+func unzipKML(b []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+	var doc *zip.File
+	for _, f := range zr.File {
+		if strings.EqualFold(path.Ext(f.Name), ".kml") && (doc == nil || f.Name == "doc.kml") {
+			doc = f
+		}
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("no kml file in the zip")
+	}
+	r, err := doc.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ioutil.ReadAll(r)
+}
+
+// Decode reads the placemarks of a KML document, wherever they are in its folders, as GPX. Each
+// placemark with a line becomes a route (a MultiGeometry of several lines becomes one route of all
+// their points) and each placemark with a point becomes a waypoint. Names and descriptions are kept,
+// so the "L001 name" convention of the files we write still identifies the leg.
+func Decode(r io.Reader) (gpx.GPX, error) {
+	g := gpx.GPX{Xmlns: gpx.Namespace, Version: "1.1", Creator: "ght"}
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return g, nil
+		}
+		if err != nil {
+			return gpx.GPX{}, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "Placemark" {
+			continue
+		}
+		var p placemarkXML
+		if err := d.DecodeElement(&p, &start); err != nil {
+			return gpx.GPX{}, err
+		}
+		name, desc := strings.TrimSpace(p.Name), strings.TrimSpace(p.Description)
+		points, lines, err := p.geometry()
+		if err != nil {
+			return gpx.GPX{}, fmt.Errorf("placemark %q: %w", name, err)
+		}
+		for _, pt := range points {
+			g.Waypoints = append(g.Waypoints, gpx.Waypoint{Point: pt, Name: name, Desc: desc})
+		}
+		if len(lines) > 0 {
+			route := gpx.Route{Name: name, Desc: desc}
+			for _, line := range lines {
+				for _, pt := range line {
+					route.Points = append(route.Points, gpx.Waypoint{Point: pt})
+				}
+			}
+			g.Routes = append(g.Routes, route)
+		}
+	}
+}
+
+type placemarkXML struct {
+	Name        string `xml:"name"`
+	Description string `xml:"description"`
+	geometryXML
+}
+
+type geometryXML struct {
+	Points        []coordinatesXML `xml:"Point"`
+	LineStrings   []coordinatesXML `xml:"LineString"`
+	MultiGeometry []geometryXML    `xml:"MultiGeometry"`
+}
+
+type coordinatesXML struct {
+	Coordinates string `xml:"coordinates"`
+}
+
+// geometry returns the points and lines of a placemark, including those nested in MultiGeometry.
+func (g geometryXML) geometry() (points []gpx.Point, lines [][]gpx.Point, err error) {
+	for _, p := range g.Points {
+		c, err := ParseCoordinates(p.Coordinates)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(c) > 0 {
+			points = append(points, c[0])
+		}
+	}
+	for _, l := range g.LineStrings {
+		c, err := ParseCoordinates(l.Coordinates)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(c) > 0 {
+			lines = append(lines, c)
+		}
+	}
+	for _, m := range g.MultiGeometry {
+		p, l, err := m.geometry()
+		if err != nil {
+			return nil, nil, err
+		}
+		points = append(points, p...)
+		lines = append(lines, l...)
+	}
+	return points, lines, nil
+}
+
+// ParseCoordinates parses KML coordinates: lon,lat or lon,lat,ele tuples separated by whitespace.
+func ParseCoordinates(s string) ([]gpx.Point, error) {
+	var points []gpx.Point
+	for _, tuple := range strings.Fields(s) {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid coordinates %q", tuple)
+		}
+		var values [3]float64
+		for i, part := range parts {
+			v, err := strconv.ParseFloat(part, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid coordinates %q", tuple)
+			}
+			values[i] = v
+		}
+		points = append(points, gpx.Point{Lon: values[0], Lat: values[1], Ele: values[2], NoEle: len(parts) == 2})
+	}
+	return points, nil
+}
@@ -0,0 +1,76 @@
+package kml
+
+import "encoding/xml"
+
+// GxNamespace is the Google extensions namespace, for tours.
+const GxNamespace = "http://www.google.com/kml/ext/2.2"
+
+// Tour is a Google Earth tour: a playlist of camera flights, pauses and balloon changes that plays
+// like a video.
+type Tour struct {
+	Name     string   `xml:"name"`
+	Playlist Playlist `xml:"gx:Playlist"`
+}
+
+// Playlist is the steps of a tour, each a *FlyTo, *Wait or *AnimatedUpdate.
+type Playlist struct {
+	Steps []interface{}
+}
+
+// FlyTo moves the camera to look at a point over Duration seconds. Mode "smooth" flies through the
+// point without stopping, and "bounce" flies up and back down as if to a new place.
+type FlyTo struct {
+	XMLName  xml.Name `xml:"gx:FlyTo"`
+	Duration float64  `xml:"gx:duration"`
+	Mode     string   `xml:"gx:flyToMode,omitempty"`
+	LookAt   LookAt   `xml:"LookAt"`
+}
+
+// LookAt is a camera position, Range m from the point it looks at, Tilt degrees from vertical and
+// facing Heading degrees from north.
+type LookAt struct {
+	Longitude    float64 `xml:"longitude"`
+	Latitude     float64 `xml:"latitude"`
+	Altitude     float64 `xml:"altitude"`
+	Heading      float64 `xml:"heading"`
+	Tilt         float64 `xml:"tilt"`
+	Range        float64 `xml:"range"`
+	AltitudeMode string  `xml:"altitudeMode,omitempty"`
+}
+
+// Wait pauses the tour for Duration seconds.
+type Wait struct {
+	XMLName  xml.Name `xml:"gx:Wait"`
+	Duration float64  `xml:"gx:duration"`
+}
+
+// AnimatedUpdate changes a feature of the document during a tour.
+type AnimatedUpdate struct {
+	XMLName  xml.Name `xml:"gx:AnimatedUpdate"`
+	Duration float64  `xml:"gx:duration"`
+	Update   Update   `xml:"Update"`
+}
+
+type Update struct {
+	TargetHref string `xml:"targetHref"`
+	Change     Change `xml:"Change"`
+}
+
+type Change struct {
+	Placemark BalloonChange `xml:"Placemark"`
+}
+
+// BalloonChange opens or closes the balloon of the placemark with id TargetId.
+type BalloonChange struct {
+	TargetId   string `xml:"targetId,attr"`
+	Visibility int    `xml:"gx:balloonVisibility"`
+}
+
+// ShowBalloon returns the step of a tour that opens or closes the balloon of a placemark.
+func ShowBalloon(id string, show bool) *AnimatedUpdate {
+	visibility := 0
+	if show {
+		visibility = 1
+	}
+	return &AnimatedUpdate{Update: Update{Change: Change{Placemark: BalloonChange{TargetId: id, Visibility: visibility}}}}
+}
@@ -0,0 +1,80 @@
+package notes
+
+import (
+	"fmt"
+	"io"
+)
+
+// Problem is a row of the trail notes that doesn't fit the schema.
+type Problem struct {
+	Tab     string // "Legs", "Waypoints" or "Passes"
+	Row     int    // row in the google sheet, counting the column names as row 1
+	Leg     int
+	Problem string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s row %d (leg %d): %s", p.Tab, p.Row, p.Leg, p.Problem)
+}
+
+// Problems lists every problem found in the trail notes, so they can all be fixed at once.
+type Problems []Problem
+
+func (p Problems) Error() string {
+	return fmt.Sprintf("%d problems in trail notes", len(p))
+}
+
+// Print writes the problems in sheet order.
+func (p Problems) Print(w io.Writer) {
+	for _, problem := range p {
+		fmt.Fprintf(w, "%s\n", problem)
+	}
+	if len(p) > 0 {
+		fmt.Fprintln(w, p.Error())
+	}
+}
+
+// Check reports bad lodge codes, ratings outside 1-5, malformed vlog days, duplicate or
+// non-contiguous leg numbers, and waypoints and passes of legs that don't exist.
+func (notes *Sheet) Check() Problems {
+	var problems Problems
+	legs := map[int]bool{}
+	for i, leg := range notes.Legs {
+		add := func(format string, args ...interface{}) {
+			problems = append(problems, Problem{Tab: "Legs", Row: i + 2, Leg: leg.Leg, Problem: fmt.Sprintf(format, args...)})
+		}
+		if legs[leg.Leg] {
+			add("duplicate leg number")
+		} else if i == 0 && leg.Leg != 1 {
+			add("first leg is not leg 1")
+		} else if i > 0 && leg.Leg != notes.Legs[i-1].Leg+1 {
+			add("leg follows leg %d", notes.Legs[i-1].Leg)
+		}
+		legs[leg.Leg] = true
+		if !leg.Lodge.Valid() {
+			add("lodge %q is not C, S, H or G", leg.Lodge)
+		}
+		for _, r := range []struct {
+			Name   string
+			Rating Rating
+		}{{"route", leg.Route}, {"trail", leg.Trail}, {"quality", leg.Quality}} {
+			if !r.Rating.Valid() {
+				add("%s rating %d is not 1-5", r.Name, r.Rating)
+			}
+		}
+		if _, err := leg.Vlog.Days(); err != nil {
+			add("%v in vlog %q", err, leg.Vlog)
+		}
+	}
+	for i, w := range notes.Waypoints {
+		if !legs[w.Leg] {
+			problems = append(problems, Problem{Tab: "Waypoints", Row: i + 2, Leg: w.Leg, Problem: fmt.Sprintf("waypoint %q is in a leg that doesn't exist", w.Name)})
+		}
+	}
+	for i, p := range notes.Passes {
+		if !legs[p.Leg] {
+			problems = append(problems, Problem{Tab: "Passes", Row: i + 2, Leg: p.Leg, Problem: fmt.Sprintf("pass %q is in a leg that doesn't exist", p.Pass)})
+		}
+	}
+	return problems
+}
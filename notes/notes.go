@@ -0,0 +1,191 @@
+// Package notes is the trail notes data model, exported from the google sheet as JSON.
+package notes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Sheet is the trail notes JSON, with one list for each tab of the google sheet.
+type Sheet struct {
+	Legs      []*Leg
+	Waypoints []*Waypoint
+	Passes    []*Pass
+}
+
+// Leg is a row of the Legs tab. The fields after Notes are filled in by Load, and aren't saved.
+type Leg struct {
+	Leg  int
+	Vlog Vlog `json:",omitempty"`
+
+	To                                              string
+	Length, Climb, Descent, Start, End, Top, Bottom float64
+	Time                                            float64 `json:",omitempty"` // walking time in hours
+	Route, Trail                                    Rating
+	Lodge                                           Lodge
+	Quality                                         Rating
+	Notes                                           string
+
+	From      string      `json:"-"`
+	Waypoints []*Waypoint `json:"-"`
+	Passes    []*Pass     `json:"-"`
+	Days      []int       `json:"-"`
+
+	RouteString   string `json:"-"`
+	TrailString   string `json:"-"`
+	LodgeString   string `json:"-"`
+	QualityString string `json:"-"`
+}
+
+// Waypoint is a row of the Waypoints tab.
+type Waypoint struct {
+	Leg       int
+	Name      string
+	Lat       float64 `json:",omitempty"`
+	Lon       float64 `json:",omitempty"`
+	Elevation float64
+	Notes     string
+}
+
+// Pass is a row of the Passes tab.
+type Pass struct {
+	Leg    int
+	Pass   string
+	Height float64
+}
+
+// Load loads the trail notes and links each leg to its waypoints and passes. The first leg starts at
+// start, and each other leg starts where the previous one finished.
+func Load(filename, start string) (*Sheet, error) {
+	notes, err := Decode(filename)
+	if err != nil {
+		return nil, err
+	}
+	if err := notes.Link(start); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// Decode loads the trail notes without linking or checking them.
+func Decode(filename string) (*Sheet, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var notes Sheet
+	if err := json.Unmarshal(b, &notes); err != nil {
+		return nil, fmt.Errorf("error decoding trail notes %q: %w", filename, err)
+	}
+	return &notes, nil
+}
+
+// Save writes the trail notes JSON in the same form as the export from the google sheet.
+func (notes *Sheet) Save(filename string) error {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(notes); err != nil {
+		return fmt.Errorf("error encoding trail notes: %w", err)
+	}
+	if err := ioutil.WriteFile(filename, bytes.TrimSuffix(buf.Bytes(), []byte("\n")), 0777); err != nil {
+		return fmt.Errorf("error writing file %q: %w", filename, err)
+	}
+	return nil
+}
+
+// Link fills in the From, Waypoints, Passes, Days and description fields of each leg. Every leg is
+// linked even if some have an invalid vlog, and the first error is returned.
+func (notes *Sheet) Link(start string) error {
+	var first error
+	for i, leg := range notes.Legs {
+		if i == 0 {
+			leg.From = start
+		} else {
+			leg.From = notes.Legs[i-1].To
+		}
+		leg.Waypoints = nil
+		for _, waypoint := range notes.Waypoints {
+			if waypoint.Leg == leg.Leg {
+				leg.Waypoints = append(leg.Waypoints, waypoint)
+			}
+		}
+		leg.Passes = nil
+		for _, pass := range notes.Passes {
+			if pass.Leg == leg.Leg {
+				leg.Passes = append(leg.Passes, pass)
+			}
+		}
+		days, err := leg.Vlog.Days()
+		if err != nil && first == nil {
+			first = fmt.Errorf("leg %d: %w", leg.Leg, err)
+		}
+		leg.Days = days
+
+		leg.TrailString = QualityString(leg.Trail, "T")
+		leg.RouteString = QualityString(leg.Route, "R")
+		leg.QualityString = QualityString(leg.Quality, string(leg.Lodge))
+		leg.LodgeString = LodgeString(leg.Lodge)
+	}
+	return first
+}
+
+// Leg returns the leg with the given number, or nil.
+func (notes *Sheet) Leg(number int) *Leg {
+	for _, leg := range notes.Legs {
+		if leg.Leg == number {
+			return leg
+		}
+	}
+	return nil
+}
+
+// QualityString describes a 1-5 rating. t is "T" for trail, "R" for route, or the lodge code for
+// accommodation.
+func QualityString(i Rating, t string) string {
+	switch i {
+	case 1:
+		switch t {
+		case "T", "R":
+			// trail or route
+			return "1/5 (major problems)"
+		case "C", "S":
+			// campsite, shelter
+			return "1/5 (awful)"
+		case "G", "H":
+			// guesthouse or homestay
+			return "1/5 (basic)"
+		default:
+			return "1/5"
+		}
+	case 2:
+		return "2/5 (below average)"
+	case 3:
+		return "3/5 (average)"
+	case 4:
+		return "4/5 (above average)"
+	case 5:
+		return "5/5 (excellent)"
+	default:
+		return "(unknown)"
+	}
+}
+
+// LodgeString describes a lodge code.
+func LodgeString(lodge Lodge) string {
+	switch lodge {
+	case Campsite:
+		return "campsite"
+	case Shelter:
+		return "shelter"
+	case Homestay:
+		return "homestay"
+	case Guesthouse:
+		return "guesthouse"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,76 @@
+package notes
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Vlog is the vlog days of a leg, e.g. "32" or "32,33". The sheet has a number for a single day and
+// text for a list of days, and it's saved the same way.
+type Vlog string
+
+func (v *Vlog) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = Vlog(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("vlog must be a number or text, got %s", b)
+	}
+	*v = Vlog(n)
+	return nil
+}
+
+func (v Vlog) MarshalJSON() ([]byte, error) {
+	if n, err := strconv.Atoi(string(v)); err == nil && strconv.Itoa(n) == string(v) {
+		return []byte(v), nil
+	}
+	return json.Marshal(string(v))
+}
+
+// Days parses the list of days.
+func (v Vlog) Days() ([]int, error) {
+	if v == "" {
+		return nil, nil
+	}
+	var days []int
+	for _, day := range strings.Split(string(v), ",") {
+		d, err := strconv.Atoi(strings.TrimSpace(day))
+		if err != nil || d < 1 {
+			return nil, fmt.Errorf("invalid vlog day %q", day)
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// Lodge is the type of accommodation at the end of a leg.
+type Lodge string
+
+const (
+	Campsite   Lodge = "C"
+	Shelter    Lodge = "S"
+	Homestay   Lodge = "H"
+	Guesthouse Lodge = "G"
+)
+
+// Valid reports whether l is one of the lodge codes.
+func (l Lodge) Valid() bool {
+	switch l {
+	case Campsite, Shelter, Homestay, Guesthouse:
+		return true
+	}
+	return false
+}
+
+// Rating is a 1-5 rating of a route, trail or accommodation.
+type Rating int
+
+// Valid reports whether r is between 1 and 5.
+func (r Rating) Valid() bool {
+	return r >= 1 && r <= 5
+}
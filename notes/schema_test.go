@@ -0,0 +1,53 @@
+package notes
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestVlog(t *testing.T) {
+	for _, test := range []struct {
+		json string
+		vlog Vlog
+		days []int
+	}{
+		{`32`, "32", []int{32}},
+		{`"32,33"`, "32,33", []int{32, 33}},
+		{`"32, 33"`, "32, 33", []int{32, 33}},
+		{`""`, "", nil},
+	} {
+		var v Vlog
+		if err := json.Unmarshal([]byte(test.json), &v); err != nil {
+			t.Errorf("%s: %v", test.json, err)
+			continue
+		}
+		if v != test.vlog {
+			t.Errorf("%s: got %q, want %q", test.json, v, test.vlog)
+		}
+		days, err := v.Days()
+		if err != nil {
+			t.Errorf("%s: %v", test.json, err)
+		}
+		if !reflect.DeepEqual(days, test.days) {
+			t.Errorf("%s: got days %v, want %v", test.json, days, test.days)
+		}
+
+		// saved the way the sheet has it
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.json {
+			t.Errorf("%s: saved as %s", test.json, b)
+		}
+	}
+
+	var v Vlog
+	if err := json.Unmarshal([]byte(`true`), &v); err == nil {
+		t.Error("expected an error for a vlog that is neither a number nor text")
+	}
+	if _, err := Vlog("32,x").Days(); err == nil {
+		t.Error("expected an error for an invalid day")
+	}
+}
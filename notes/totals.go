@@ -0,0 +1,48 @@
+package notes
+
+// Totals are the cumulative stats of a run of legs: the whole trail or a section of it. Length is in
+// km, Time in hours and everything else in m.
+type Totals struct {
+	Name                         string
+	FirstLeg, LastLeg            int
+	Legs                         int
+	Length, Climb, Descent, Time float64
+	Highest                      float64 // top of the highest leg
+	HighestLeg                   int
+	HighPasses                   int // passes over 5,000 m
+	Camping, Guesthouse          int // nights at campsites or shelters, and at guesthouses or homestays
+	From, To                     string
+}
+
+// Total adds up the stats of legs, which must be linked.
+func Total(name string, legs []*Leg) Totals {
+	t := Totals{Name: name, Legs: len(legs)}
+	for i, leg := range legs {
+		if i == 0 {
+			t.FirstLeg = leg.Leg
+			t.From = leg.From
+		}
+		t.LastLeg = leg.Leg
+		t.To = leg.To
+		t.Length += leg.Length
+		t.Climb += leg.Climb
+		t.Descent += leg.Descent
+		t.Time += leg.Time
+		if i == 0 || leg.Top > t.Highest {
+			t.Highest = leg.Top
+			t.HighestLeg = leg.Leg
+		}
+		for _, pass := range leg.Passes {
+			if pass.Height > 5000 {
+				t.HighPasses++
+			}
+		}
+		switch leg.Lodge {
+		case Campsite, Shelter:
+			t.Camping++
+		case Guesthouse, Homestay:
+			t.Guesthouse++
+		}
+	}
+	return t
+}
@@ -0,0 +1,134 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+
+	"github.com/dave/ght/dem"
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/project"
+)
+
+// CorrectElevations samples the DEM tiles for every route point and waypoint of each leg, writes the
+// GPX files with the corrected elevations, and prints the largest discrepancies of each leg.
+func CorrectElevations(t *project.Trail, opts Options) error {
+	if t.DEM == "" {
+		return fmt.Errorf("dem is not set in the config file")
+	}
+	d, err := dem.Open(t.DEM)
+	if err != nil {
+		return err
+	}
+	out := t.Output.Corrected
+	if err := opts.mkdir("output.corrected", out); err != nil {
+		return err
+	}
+	routeFiles, err := ioutil.ReadDir(t.Gpx)
+	if err != nil {
+		return err
+	}
+
+	type change struct {
+		Name     string
+		Point    gpx.Point
+		Old, New float64
+	}
+
+	var failed LegErrors
+	for _, fileInfo := range routeFiles {
+		leg, ok := t.Leg(fileInfo.Name())
+		if !ok || !opts.Legs.Include(leg) {
+			continue
+		}
+		g, err := gpx.Load(filepath.Join(t.Gpx, fileInfo.Name()))
+		if err != nil {
+			failed.Add(leg, err)
+			continue
+		}
+
+		var changes []change
+		var missing, added int
+		var total float64
+		correct := func(name string, w *gpx.Waypoint) error {
+			ele, ok, err := d.Elevation(w.Lat, w.Lon)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				missing++
+				return nil
+			}
+			ele = math.Round(ele*10) / 10
+			if w.NoEle {
+				// nothing to compare with
+				added++
+				w.Ele, w.NoEle = ele, false
+				return nil
+			}
+			changes = append(changes, change{Name: name, Point: w.Point, Old: w.Ele, New: ele})
+			total += math.Abs(ele - w.Ele)
+			w.Ele = ele
+			return nil
+		}
+		var points []*gpx.Waypoint
+		var names []string
+		for i := range g.Waypoints {
+			points = append(points, &g.Waypoints[i])
+			names = append(names, fmt.Sprintf("waypoint %q", g.Waypoints[i].Name))
+		}
+		for _, r := range g.Routes {
+			for i := range r.Points {
+				points = append(points, &r.Points[i])
+				names = append(names, fmt.Sprintf("route point %d", i))
+			}
+		}
+		for _, trk := range g.Tracks {
+			for _, seg := range trk.Segments {
+				for i := range seg.Points {
+					points = append(points, &seg.Points[i])
+					names = append(names, fmt.Sprintf("track point %d", i))
+				}
+			}
+		}
+		for i, w := range points {
+			if err = correct(names[i], w); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			failed.Add(leg, err)
+			continue
+		}
+
+		fmt.Printf("L%03d %d points", leg, len(points))
+		if len(changes) > 0 {
+			fmt.Printf(", mean change %.0f m", total/float64(len(changes)))
+		}
+		if added > 0 {
+			fmt.Printf(", %d given an elevation", added)
+		}
+		if missing > 0 {
+			fmt.Printf(", %d with no DEM data", missing)
+		}
+		fmt.Println()
+		sort.SliceStable(changes, func(i, j int) bool {
+			return math.Abs(changes[i].New-changes[i].Old) > math.Abs(changes[j].New-changes[j].Old)
+		})
+		for i, c := range changes {
+			if i == 5 || math.Abs(c.New-c.Old) < 1 {
+				break
+			}
+			fmt.Printf("  %s (%.5f, %.5f): %.0f m -> %.0f m (%+.0f m)\n", c.Name, c.Point.Lat, c.Point.Lon, c.Old, c.New, c.New-c.Old)
+		}
+
+		if fpath := filepath.Join(out, fileInfo.Name()); !opts.skip(fpath) {
+			if err := gpx.Save(g, fpath); err != nil {
+				failed.Add(leg, err)
+			}
+		}
+	}
+	return failed.Err()
+}
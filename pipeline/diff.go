@@ -0,0 +1,278 @@
+package pipeline
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dave/ght/geo"
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/project"
+	"github.com/dave/ght/stats"
+)
+
+// Diff compares the routes files of the -since version and the current version, and with -old-notes
+// a trail notes snapshot with the current trail notes, and writes the changes to each leg to stdout in
+// markdown for the changelog.
+func Diff(t *project.Trail, opts Options) error {
+	since := opts.Since
+	if since == 0 {
+		since = opts.Version - 1
+	}
+	threshold := opts.Threshold
+	alg, err := opts.climb(t)
+	if err != nil {
+		return err
+	}
+	model, err := opts.walking(t)
+	if err != nil {
+		return err
+	}
+	if t.Output.Routes == "" {
+		return fmt.Errorf("output.routes is not set in the config file")
+	}
+	oldRoutes, err := gpx.Load(filepath.Join(t.Output.Routes, fmt.Sprintf("routes-v%v.gpx", since)))
+	if err != nil {
+		return err
+	}
+	newRoutes, err := gpx.Load(filepath.Join(t.Output.Routes, fmt.Sprintf("routes-v%v.gpx", opts.Version)))
+	if err != nil {
+		return err
+	}
+
+	changes := map[int][]string{}
+	names := map[int]string{}
+	change := func(leg int, format string, args ...interface{}) {
+		changes[leg] = append(changes[leg], fmt.Sprintf(format, args...))
+	}
+
+	oldLegs, newLegs := routesByLeg(oldRoutes), routesByLeg(newRoutes)
+	for leg, o := range oldLegs {
+		if _, ok := newLegs[leg]; !ok && opts.Legs.Include(leg) {
+			names[leg] = o.Name
+			change(leg, "Leg removed.")
+		}
+	}
+	for leg, n := range newLegs {
+		if !opts.Legs.Include(leg) {
+			continue
+		}
+		names[leg] = n.Name
+		o, ok := oldLegs[leg]
+		if !ok {
+			change(leg, "Leg added.")
+			continue
+		}
+		if o.Name != n.Name {
+			change(leg, "Renamed from %q.", o.Name)
+		}
+		oldPoints, newPoints := gpx.Locations(o.Points), gpx.Locations(n.Points)
+		if d, at := deviation(oldPoints, newPoints); d > threshold {
+			change(leg, "Route moved by up to %.0f m near %.5f, %.5f.", d, at.Lat, at.Lon)
+		}
+		before, after := stats.Calc(oldPoints, alg, model), stats.Calc(newPoints, alg, model)
+		if a, b := fmt.Sprintf("%.1f", before.Length), fmt.Sprintf("%.1f", after.Length); a != b {
+			change(leg, "Length changed from %s km to %s km.", a, b)
+		}
+		if a, b := math.Round(before.Climb/10)*10, math.Round(after.Climb/10)*10; a != b {
+			change(leg, "Climb changed from %.0f m to %.0f m.", a, b)
+		}
+		if a, b := math.Round(before.Descent/10)*10, math.Round(after.Descent/10)*10; a != b {
+			change(leg, "Descent changed from %.0f m to %.0f m.", a, b)
+		}
+	}
+
+	oldWaypoints, newWaypoints := waypointsByLeg(oldRoutes), waypointsByLeg(newRoutes)
+	for leg := range newLegs {
+		if !opts.Legs.Include(leg) {
+			continue
+		}
+		if _, ok := oldLegs[leg]; !ok {
+			continue
+		}
+		var added, removed []gpx.Waypoint
+		for _, n := range newWaypoints[leg] {
+			found := false
+			for _, o := range oldWaypoints[leg] {
+				if o.Name == n.Name {
+					found = true
+					if d := geo.Distance(o.Lat, o.Lon, n.Lat, n.Lon) * 1000; d > threshold {
+						change(leg, "Waypoint %q moved %.0f m.", waypointName(leg, n), d)
+					}
+				}
+			}
+			if !found {
+				added = append(added, n)
+			}
+		}
+		for _, o := range oldWaypoints[leg] {
+			found := false
+			for _, n := range newWaypoints[leg] {
+				found = found || o.Name == n.Name
+			}
+			if !found {
+				removed = append(removed, o)
+			}
+		}
+		// an added waypoint close to a removed one is the same waypoint with a new name
+		for _, n := range added {
+			renamed := false
+			for i, o := range removed {
+				if geo.Distance(o.Lat, o.Lon, n.Lat, n.Lon)*1000 <= threshold {
+					change(leg, "Waypoint %q renamed to %q.", waypointName(leg, o), waypointName(leg, n))
+					removed = append(removed[:i], removed[i+1:]...)
+					renamed = true
+					break
+				}
+			}
+			if !renamed {
+				change(leg, "Waypoint %q added.", waypointName(leg, n))
+			}
+		}
+		for _, o := range removed {
+			change(leg, "Waypoint %q removed.", waypointName(leg, o))
+		}
+	}
+
+	if opts.OldNotes != "" {
+		oldNotes, err := notes.Decode(opts.OldNotes)
+		if err != nil {
+			return err
+		}
+		newNotes, err := notes.Decode(t.Notes)
+		if err != nil {
+			return err
+		}
+		// invalid vlogs don't matter here
+		_ = oldNotes.Link(t.Start)
+		_ = newNotes.Link(t.Start)
+		for _, n := range newNotes.Legs {
+			o := oldNotes.Leg(n.Leg)
+			if o == nil || !opts.Legs.Include(n.Leg) {
+				continue
+			}
+			if names[n.Leg] == "" {
+				names[n.Leg] = fmt.Sprintf("L%03d %s to %s", n.Leg, n.From, n.To)
+			}
+			if o.To != n.To {
+				change(n.Leg, "Destination changed from %s to %s.", o.To, n.To)
+			}
+			if o.Notes != n.Notes {
+				change(n.Leg, "Notes edited.")
+			}
+			if o.Lodge != n.Lodge {
+				change(n.Leg, "Accommodation changed from %s to %s.", o.LodgeString, n.LodgeString)
+			}
+			for _, r := range []struct {
+				Name     string
+				Old, New notes.Rating
+			}{{"Route rating", o.Route, n.Route}, {"Trail rating", o.Trail, n.Trail}, {"Accommodation rating", o.Quality, n.Quality}} {
+				if r.Old != r.New {
+					change(n.Leg, "%s changed from %d to %d.", r.Name, r.Old, r.New)
+				}
+			}
+			for _, nw := range n.Waypoints {
+				for _, ow := range o.Waypoints {
+					if ow.Name == nw.Name && ow.Notes != nw.Notes {
+						change(n.Leg, "Notes of waypoint %q edited.", nw.Name)
+					}
+				}
+			}
+		}
+	}
+
+	var legs []int
+	for leg := range changes {
+		legs = append(legs, leg)
+	}
+	sort.Ints(legs)
+	fmt.Printf("## Changes from version %d to version %d\n\n", since, opts.Version)
+	if len(legs) == 0 {
+		fmt.Println("No changes.")
+	}
+	for _, leg := range legs {
+		fmt.Printf("### %s\n\n", strings.Replace(names[leg], "|", "\\|", -1))
+		for _, c := range changes[leg] {
+			fmt.Printf("* %s\n", c)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// routesByLeg returns the routes of a routes file by leg, from the "L001" at the start of each name.
+func routesByLeg(g gpx.GPX) map[int]gpx.Route {
+	routes := map[int]gpx.Route{}
+	for _, r := range g.Routes {
+		var leg int
+		if _, err := fmt.Sscanf(r.Name, "L%03d", &leg); err == nil {
+			routes[leg] = r
+		}
+	}
+	return routes
+}
+
+// waypointsByLeg returns the waypoints of a routes file by leg, from the "L001" at the start of each
+// name.
+func waypointsByLeg(g gpx.GPX) map[int][]gpx.Waypoint {
+	waypoints := map[int][]gpx.Waypoint{}
+	for _, w := range g.Waypoints {
+		var leg int
+		if _, err := fmt.Sscanf(w.Name, "L%03d", &leg); err == nil {
+			waypoints[leg] = append(waypoints[leg], w)
+		}
+	}
+	return waypoints
+}
+
+// waypointName returns the name of a waypoint without the leg.
+func waypointName(leg int, w gpx.Waypoint) string {
+	return strings.TrimPrefix(w.Name, fmt.Sprintf("L%03d ", leg))
+}
+
+// deviation returns the furthest distance in m of a point on either route from the other route, and
+// where it is.
+func deviation(a, b []gpx.Point) (float64, gpx.Point) {
+	var max float64
+	var at gpx.Point
+	for _, pair := range [][2][]gpx.Point{{a, b}, {b, a}} {
+		for _, p := range pair[0] {
+			if d := distanceToRoute(p, pair[1]); d > max {
+				max, at = d, p
+			}
+		}
+	}
+	return max, at
+}
+
+// distanceToRoute returns the distance in m from p to the nearest segment of a route, treating the
+// ground as flat around p.
+func distanceToRoute(p gpx.Point, route []gpx.Point) float64 {
+	const metresPerDegree = 111195.0
+	scale := math.Cos(p.Lat * math.Pi / 180)
+	xy := func(q gpx.Point) (float64, float64) {
+		return (q.Lon - p.Lon) * metresPerDegree * scale, (q.Lat - p.Lat) * metresPerDegree
+	}
+	min := math.Inf(1)
+	for i := range route {
+		x1, y1 := xy(route[i])
+		if i == 0 {
+			min = math.Hypot(x1, y1)
+			continue
+		}
+		x0, y0 := xy(route[i-1])
+		// the closest point to p (the origin) on the segment
+		dx, dy := x1-x0, y1-y0
+		f := 0.0
+		if l := dx*dx + dy*dy; l > 0 {
+			f = math.Max(0, math.Min(1, -(x0*dx+y0*dy)/l))
+		}
+		if d := math.Hypot(x0+f*dx, y0+f*dy); d < min {
+			min = d
+		}
+	}
+	return min
+}
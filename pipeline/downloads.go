@@ -0,0 +1,204 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/kml"
+	"github.com/dave/ght/project"
+)
+
+// Download is a file in the manifest of the downloads page.
+type Download struct {
+	File    string `json:"file"`
+	Format  string `json:"format"`            // gpx or kml
+	Leg     int    `json:"leg,omitempty"`     // the leg the file is for, or
+	Section string `json:"section,omitempty"` // the slug of the section the file is for
+	Name    string `json:"name"`
+	Size    int    `json:"size"` // bytes
+	SHA256  string `json:"sha256"`
+}
+
+// Manifest lists the files written by WriteDownloads, so the downloads page can show their sizes and
+// checksums.
+type Manifest struct {
+	Trail   string     `json:"trail"`
+	Version int        `json:"version"`
+	Files   []Download `json:"files"`
+}
+
+// WriteDownloads writes a GPX and a KML file for each leg and each section, with the route,
+// waypoints and description from the trail notes, so hikers can download just the part of the trail
+// they're walking. The files are listed in manifest-vN.json, which always lists every leg and
+// section: -legs only limits the files that are rewritten. Legs that fail are reported at the end,
+// the files of the sections they're in aren't written, so they never have legs missing, and the
+// files of both keep their entries from the previous manifest.
+func WriteDownloads(t *project.Trail, opts Options) error {
+
+	outDir := t.Output.Downloads
+	if err := opts.mkdir("output.downloads", outDir); err != nil {
+		return err
+	}
+
+	// the sections and the manifest need every leg
+	all := opts
+	all.Legs = nil
+	legs, legsFailed := legRoutes(t, nil, all)
+	if len(legs) == 0 && legsFailed != nil {
+		return legsFailed
+	}
+	skipped := failedLegs(legsFailed)
+
+	manifest := Manifest{Trail: t.Name, Version: opts.Version, Files: []Download{}}
+	// write adds the files to the manifest, and writes them if they're selected
+	write := func(base string, d Download, g gpx.GPX, legs []legRoute, selected bool) error {
+		gpxData, err := gpx.Marshal(g)
+		if err != nil {
+			return fmt.Errorf("error encoding gpx for %s: %w", base, err)
+		}
+		kmlData, err := kml.Marshal(kml.FromSections(d.Name, []kml.Section{{Waypoints: g.Waypoints, Routes: g.Routes}}, kmlStyling(t, legs, t.KML.ColorBy)))
+		if err != nil {
+			return fmt.Errorf("error encoding kml for %s: %w", base, err)
+		}
+		for _, f := range []struct {
+			Format string
+			Data   []byte
+		}{{"gpx", gpxData}, {"kml", kmlData}} {
+			d.File = fmt.Sprintf("%s-v%v.%s", base, opts.Version, f.Format)
+			d.Format = f.Format
+			d.Size = len(f.Data)
+			sum := sha256.Sum256(f.Data)
+			d.SHA256 = hex.EncodeToString(sum[:])
+			manifest.Files = append(manifest.Files, d)
+			if fpath := filepath.Join(outDir, d.File); selected && !opts.skip(fpath) {
+				if err := ioutil.WriteFile(fpath, f.Data, 0777); err != nil {
+					return fmt.Errorf("error writing file %q: %w", fpath, err)
+				}
+			}
+		}
+		return nil
+	}
+
+	var failed LegErrors
+	for _, leg := range legs {
+		g := downloadGpx(leg.Route.Name, leg.Notes.Notes, leg)
+		d := Download{Leg: leg.Leg, Name: leg.Route.Name}
+		if err := write(fmt.Sprintf("L%03d", leg.Leg), d, g, []legRoute{leg}, opts.Legs.Include(leg.Leg)); err != nil {
+			failed.Add(leg.Leg, err)
+		}
+	}
+	for _, f := range failed {
+		skipped[f.Leg] = true
+	}
+
+	var sections int
+	skippedSections := map[string]bool{}
+	for _, s := range t.Sections {
+		var included []legRoute
+		var selected bool
+		for _, leg := range legs {
+			if s.Includes(leg.Leg) {
+				included = append(included, leg)
+				selected = selected || opts.Legs.Include(leg.Leg)
+			}
+		}
+		if len(included) == 0 {
+			continue
+		}
+		if missing := sectionMissing(s, skipped); missing != 0 {
+			fmt.Fprintf(os.Stderr, "section %q skipped: leg %d failed\n", s.Name, missing)
+			skippedSections[s.Slug()] = true
+			continue
+		}
+		sections++
+		name := fmt.Sprintf("%s: %s", t.Name, s.Name)
+		d := Download{Section: s.Slug(), Name: name}
+		if err := write(s.Slug(), d, downloadGpx(name, s.Description, included...), included, selected); err != nil {
+			return err
+		}
+	}
+
+	// the files that weren't written this time are still there from the previous run
+	fpath := filepath.Join(outDir, fmt.Sprintf("manifest-v%v.json", opts.Version))
+	if b, err := ioutil.ReadFile(fpath); err == nil {
+		var previous Manifest
+		if err := json.Unmarshal(b, &previous); err != nil {
+			return fmt.Errorf("error decoding %q: %w", fpath, err)
+		}
+		for _, d := range previous.Files {
+			if d.Leg != 0 && skipped[d.Leg] || d.Section != "" && skippedSections[d.Section] {
+				manifest.Files = append(manifest.Files, d)
+			}
+		}
+	}
+	sortDownloads(t, manifest.Files)
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if !opts.skip(fpath) {
+		if err := ioutil.WriteFile(fpath, b, 0777); err != nil {
+			return fmt.Errorf("error writing file %q: %w", fpath, err)
+		}
+	}
+	fmt.Printf("%d files for %d legs and %d sections\n", len(manifest.Files), len(legs), sections)
+	if err := failed.Err(); err != nil {
+		return err
+	}
+	return legsFailed
+}
+
+// sectionMissing returns the first leg of a section that failed, or 0.
+func sectionMissing(s *project.Section, failed map[int]bool) int {
+	for leg := s.From; leg <= s.To; leg++ {
+		if failed[leg] {
+			return leg
+		}
+	}
+	return 0
+}
+
+// sortDownloads sorts the files of a manifest by leg, then by section in the order of the trail's
+// sections.
+func sortDownloads(t *project.Trail, files []Download) {
+	order := map[string]int{}
+	for i, s := range t.Sections {
+		order[s.Slug()] = i
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if (a.Leg == 0) != (b.Leg == 0) {
+			return a.Leg != 0
+		}
+		if a.Leg != b.Leg {
+			return a.Leg < b.Leg
+		}
+		return order[a.Section] < order[b.Section]
+	})
+}
+
+// downloadGpx returns a GPX file of the routes and waypoints of legs.
+func downloadGpx(name, desc string, legs ...legRoute) gpx.GPX {
+	g := gpx.GPX{
+		Xmlns:   gpx.Namespace,
+		Version: "1.1",
+		Creator: "ght",
+		Metadata: &gpx.Metadata{
+			Name: name,
+			Desc: desc,
+		},
+	}
+	for _, leg := range legs {
+		g.Routes = append(g.Routes, leg.Route)
+		g.Waypoints = append(g.Waypoints, leg.Waypoints...)
+	}
+	return g
+}
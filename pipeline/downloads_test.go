@@ -0,0 +1,114 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// manifestFiles returns the files in the manifest of a version.
+func manifestFiles(t *testing.T, dir string, version int) []string {
+	t.Helper()
+	b, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("manifest-v%d.json", version)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	var files []string
+	for _, f := range m.Files {
+		files = append(files, f.File)
+	}
+	return files
+}
+
+func TestWriteDownloadsFailedLeg(t *testing.T) {
+	tr := testTrail(t, 4, `    sections:
+      - {name: East, from: 1, to: 2}
+      - {name: West, from: 3, to: 4}
+`)
+	if err := ioutil.WriteFile(filepath.Join(tr.Gpx, "L002.gpx"), []byte("not xml"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	err := WriteDownloads(tr, Options{Version: 1})
+	var failed LegErrors
+	if !errors.As(err, &failed) || len(failed) != 1 || failed[0].Leg != 2 {
+		t.Fatalf("got error %v, want leg 2 failed", err)
+	}
+
+	// the other legs are written, and the section with the failed leg isn't
+	want := []string{
+		"L001-v1.gpx", "L001-v1.kml",
+		"L003-v1.gpx", "L003-v1.kml",
+		"L004-v1.gpx", "L004-v1.kml",
+		"west-v1.gpx", "west-v1.kml",
+	}
+	if got := manifestFiles(t, tr.Output.Downloads, 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("got files %v, want %v", got, want)
+	}
+	for _, name := range want {
+		if _, err := ioutil.ReadFile(filepath.Join(tr.Output.Downloads, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestWriteDownloadsLegs(t *testing.T) {
+	tr := testTrail(t, 4, `    sections:
+      - {name: East, from: 1, to: 2}
+      - {name: West, from: 3, to: 4}
+`)
+	if err := WriteDownloads(tr, Options{Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	all := manifestFiles(t, tr.Output.Downloads, 1)
+	if len(all) != 12 {
+		t.Fatalf("got files %v, want 12", all)
+	}
+	for _, name := range all {
+		if err := os.Remove(filepath.Join(tr.Output.Downloads, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// only the files of leg 2 and its section are rewritten, and the manifest still has every file
+	legs := LegSet{}
+	if err := legs.Set("2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteDownloads(tr, Options{Version: 1, Legs: legs}); err != nil {
+		t.Fatal(err)
+	}
+	if got := manifestFiles(t, tr.Output.Downloads, 1); !reflect.DeepEqual(got, all) {
+		t.Errorf("got files %v, want %v", got, all)
+	}
+	files, err := ioutil.ReadDir(tr.Output.Downloads)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written []string
+	for _, f := range files {
+		written = append(written, f.Name())
+	}
+	if want := []string{"L002-v1.gpx", "L002-v1.kml", "east-v1.gpx", "east-v1.kml", "manifest-v1.json"}; !reflect.DeepEqual(written, want) {
+		t.Errorf("got written files %v, want %v", written, want)
+	}
+
+	// a leg that fails keeps its files, and the files of its section, from the previous run
+	if err := ioutil.WriteFile(filepath.Join(tr.Gpx, "L004.gpx"), []byte("not xml"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteDownloads(tr, Options{Version: 1}); err == nil {
+		t.Fatal("expected leg 4 to fail")
+	}
+	if got := manifestFiles(t, tr.Output.Downloads, 1); !reflect.DeepEqual(got, all) {
+		t.Errorf("got files %v, want %v", got, all)
+	}
+}
@@ -0,0 +1,186 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/project"
+	"github.com/dave/ght/render"
+)
+
+// DrawMaps draws maps of each day of the route using OpenStreetMap and the GPX routes
+func DrawMaps(t *project.Trail, opts Options) error {
+	dir := t.Gpx
+	out := t.Output.Maps
+	if err := opts.mkdir("output.maps", out); err != nil {
+		return err
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	minZoom := 0
+	type data struct {
+		Leg int
+		Gpx gpx.GPX
+	}
+	var routes []data
+	var legs []int
+	var failed LegErrors
+	routesM := map[int]gpx.GPX{}
+	for _, file := range files {
+		leg, ok := t.Leg(file.Name())
+		if !ok {
+			continue
+		}
+		g, err := gpx.Load(filepath.Join(dir, file.Name()))
+		if err != nil {
+			if opts.Legs.Include(leg) {
+				failed.Add(leg, err)
+			}
+			continue
+		}
+		routesM[leg] = g
+		legs = append(legs, leg)
+	}
+	sort.Ints(legs)
+	for _, leg := range legs {
+		routes = append(routes, data{
+			Leg: leg,
+			Gpx: routesM[leg],
+		})
+	}
+
+	for i, dat := range routes {
+		if !opts.Legs.Include(dat.Leg) {
+			continue
+		}
+
+		fpath := filepath.Join(out, fmt.Sprintf("L%03d.jpg", dat.Leg))
+		if opts.skip(fpath) {
+			continue
+		}
+
+		pts, err := dat.Gpx.Points()
+		if err != nil {
+			failed.Add(dat.Leg, err)
+			continue
+		}
+
+		var others [][]gpx.Point
+		for _, j := range []int{i - 1, i - 2, i + 1, i + 2} {
+			if j < 0 || j >= len(routes) {
+				continue
+			}
+			if other, err := routes[j].Gpx.Points(); err == nil {
+				others = append(others, other)
+			}
+		}
+
+		img, zoom, err := render.Map(t, dat.Leg, pts, dat.Gpx.Waypoints, others)
+		if err != nil {
+			failed.Add(dat.Leg, fmt.Errorf("error rendering map: %w", err))
+			continue
+		}
+		if minZoom == 0 || zoom < minZoom {
+			minZoom = zoom
+		}
+		fmt.Printf("L%03d zoom %d\n", dat.Leg, zoom)
+
+		if err := writeJpeg(img, fpath); err != nil {
+			failed.Add(dat.Leg, err)
+			continue
+		}
+	}
+	fmt.Printf("minimum zoom %d\n", minZoom)
+
+	// overview maps of the sections with any legs we're drawing
+	for _, s := range t.Sections {
+		var legs []int
+		var pts [][]gpx.Point
+		var include bool
+		for _, dat := range routes {
+			if !s.Includes(dat.Leg) {
+				continue
+			}
+			include = include || opts.Legs.Include(dat.Leg)
+			if points, err := dat.Gpx.Points(); err == nil {
+				legs = append(legs, dat.Leg)
+				pts = append(pts, points)
+			}
+		}
+		if !include {
+			continue
+		}
+		fpath := filepath.Join(out, fmt.Sprintf("S-%s.jpg", s.Slug()))
+		if opts.skip(fpath) {
+			continue
+		}
+		img, zoom, err := render.SectionMap(legs, pts)
+		if err != nil {
+			failed.Add(s.From, fmt.Errorf("error rendering map of section %q: %w", s.Name, err))
+			continue
+		}
+		fmt.Printf("%s zoom %d\n", s.Name, zoom)
+		if err := writeJpeg(img, fpath); err != nil {
+			failed.Add(s.From, err)
+		}
+	}
+	return failed.Err()
+}
+
+func writeJpeg(img image.Image, fpath string) error {
+	buf := &bytes.Buffer{}
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return fmt.Errorf("error encoding map: %w", err)
+	}
+	if err := ioutil.WriteFile(fpath, buf.Bytes(), 0777); err != nil {
+		return fmt.Errorf("error writing file %q: %w", fpath, err)
+	}
+	return nil
+}
+
+func DrawElevations(t *project.Trail, opts Options) error {
+	dir := t.Gpx
+	out := t.Output.Elevations
+	if err := opts.mkdir("output.elevations", out); err != nil {
+		return err
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	var failed LegErrors
+	for _, file := range files {
+		leg, ok := t.Leg(file.Name())
+		if !ok || !opts.Legs.Include(leg) {
+			continue
+		}
+		g, err := gpx.Load(filepath.Join(dir, file.Name()))
+		if err != nil {
+			failed.Add(leg, err)
+			continue
+		}
+		pts, err := g.Points()
+		if err != nil {
+			failed.Add(leg, err)
+			continue
+		}
+		fmt.Printf("L%03d %d points\n", leg, len(pts))
+
+		if fpath := filepath.Join(out, fmt.Sprintf("E%03d.png", leg)); !opts.skip(fpath) {
+			if err := render.WriteChart(render.Elevation(pts), fpath); err != nil {
+				failed.Add(leg, err)
+			}
+		}
+	}
+
+	return failed.Err()
+}
@@ -0,0 +1,53 @@
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LegError is a failure processing a single leg.
+type LegError struct {
+	Leg int
+	Err error
+}
+
+func (e LegError) Error() string {
+	return fmt.Sprintf("leg %d: %v", e.Leg, e.Err)
+}
+
+func (e LegError) Unwrap() error {
+	return e.Err
+}
+
+// LegErrors collects the legs that failed during a run, so one bad file doesn't stop the rest of the
+// legs from being processed. The error message is a summary of every leg that failed.
+type LegErrors []LegError
+
+// Add records a failed leg and reports it straight away.
+func (e *LegErrors) Add(leg int, err error) {
+	fmt.Fprintf(os.Stderr, "leg %d failed: %v\n", leg, err)
+	*e = append(*e, LegError{Leg: leg, Err: err})
+}
+
+// Err returns nil if no legs failed.
+func (e LegErrors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e LegErrors) Error() string {
+	var sb strings.Builder
+	if len(e) == 1 {
+		sb.WriteString("1 leg failed:")
+	} else {
+		fmt.Fprintf(&sb, "%d legs failed:", len(e))
+	}
+	for _, le := range e {
+		sb.WriteString("\n  ")
+		sb.WriteString(le.Error())
+	}
+	return sb.String()
+}
@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dave/ght/project"
+)
+
+// testTrail writes a project of legs legs, each with a GPX file of a short route and the waypoint
+// at its end, with the config after the trail's required settings, and returns the trail.
+func testTrail(t *testing.T, legs int, config string) *project.Trail {
+	t.Helper()
+	dir := t.TempDir()
+	write := func(name string, b []byte) {
+		t.Helper()
+		fpath := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fpath), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(fpath, b, 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	type leg struct {
+		Leg                 int
+		To                  string
+		Length, Climb       float64
+		Descent, Start, End float64
+		Top, Bottom         float64
+		Notes               string
+	}
+	type waypoint struct {
+		Leg       int
+		Name      string
+		Elevation float64
+	}
+	var trailNotes struct {
+		Legs      []leg
+		Waypoints []waypoint
+		Passes    []struct{}
+	}
+	trailNotes.Passes = []struct{}{}
+	for i := 1; i <= legs; i++ {
+		town := fmt.Sprintf("Town %d", i)
+		trailNotes.Legs = append(trailNotes.Legs, leg{Leg: i, To: town, Length: 1, Notes: fmt.Sprintf("Notes of leg %d.", i)})
+		trailNotes.Waypoints = append(trailNotes.Waypoints, waypoint{Leg: i, Name: town, Elevation: 1000})
+		lat := 27 + float64(i)/100
+		write(fmt.Sprintf("data/gpx/L%03d.gpx", i), []byte(fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<gpx xmlns="http://www.topografix.com/GPX/1/1" version="1.1">
+	<wpt lat="%v" lon="87.01"><ele>1000</ele><name>L%03d %s</name></wpt>
+	<rte>
+		<rtept lat="%v" lon="87"><ele>1000</ele></rtept>
+		<rtept lat="%v" lon="87.005"><ele>1010</ele></rtept>
+		<rtept lat="%v" lon="87.01"><ele>1000</ele></rtept>
+	</rte>
+</gpx>`, lat, i, town, lat, lat, lat)))
+	}
+	b, err := json.Marshal(trailNotes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write("trailnotes.json", b)
+	write("ght.yaml", []byte(`trails:
+  - name: Test Trail
+    slug: test-trail
+    start: Town 0
+    notes: trailnotes.json
+    gpx: data/gpx
+    output:
+      routes: out/routes
+      downloads: out/downloads
+`+config))
+
+	p, err := project.Load(filepath.Join(dir, "ght.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p.Trails[0]
+}
@@ -0,0 +1,131 @@
+package pipeline
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/stats"
+)
+
+// statsColumns are the columns of the stats table, with units in the names so scripts don't have to
+// guess.
+var statsColumns = []struct {
+	Name  string
+	Value func(l *notes.Leg) interface{}
+}{
+	{"leg", func(l *notes.Leg) interface{} { return l.Leg }},
+	{"from", func(l *notes.Leg) interface{} { return l.From }},
+	{"to", func(l *notes.Leg) interface{} { return l.To }},
+	{"length_km", func(l *notes.Leg) interface{} { return l.Length }},
+	{"climb_m", func(l *notes.Leg) interface{} { return l.Climb }},
+	{"descent_m", func(l *notes.Leg) interface{} { return l.Descent }},
+	{"start_m", func(l *notes.Leg) interface{} { return l.Start }},
+	{"end_m", func(l *notes.Leg) interface{} { return l.End }},
+	{"top_m", func(l *notes.Leg) interface{} { return l.Top }},
+	{"bottom_m", func(l *notes.Leg) interface{} { return l.Bottom }},
+	{"time_h", func(l *notes.Leg) interface{} { return l.Time }},
+}
+
+// statsFormats write the stats table of the legs, recording the climb algorithm and walking time
+// model they were calculated with.
+var statsFormats = map[string]func(w io.Writer, legs []*notes.Leg, a stats.Algorithm, m stats.TimeModel) error{
+	"csv":      writeStatsCSV,
+	"json":     writeStatsJSON,
+	"markdown": writeStatsMarkdown,
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return formatFloat(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func writeStatsCSV(w io.Writer, legs []*notes.Leg, a stats.Algorithm, m stats.TimeModel) error {
+	cw := csv.NewWriter(w)
+	var header []string
+	for _, c := range statsColumns {
+		header = append(header, c.Name)
+	}
+	header = append(header, "climb_algorithm", "walking_model")
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, l := range legs {
+		var record []string
+		for _, c := range statsColumns {
+			record = append(record, formatValue(c.Value(l)))
+		}
+		record = append(record, a.Name, m.Name)
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeStatsJSON(w io.Writer, legs []*notes.Leg, a stats.Algorithm, m stats.TimeModel) error {
+	type leg map[string]interface{}
+	out := struct {
+		ClimbAlgorithm string `json:"climb_algorithm"`
+		WalkingModel   string `json:"walking_model"`
+		Legs           []leg  `json:"legs"`
+	}{
+		ClimbAlgorithm: a.Name,
+		WalkingModel:   m.Name,
+		Legs:           []leg{},
+	}
+	for _, l := range legs {
+		row := leg{}
+		for _, c := range statsColumns {
+			row[c.Name] = c.Value(l)
+		}
+		out.Legs = append(out.Legs, row)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func writeStatsMarkdown(w io.Writer, legs []*notes.Leg, a stats.Algorithm, m stats.TimeModel) error {
+	fmt.Fprintf(w, "Climb algorithm: %s. Walking time model: %s.\n\n", a, m)
+	for _, c := range statsColumns {
+		fmt.Fprintf(w, "| %s ", c.Name)
+	}
+	fmt.Fprintln(w, "|")
+	for _, c := range statsColumns {
+		if _, ok := c.Value(&notes.Leg{}).(string); ok {
+			fmt.Fprint(w, "| - ")
+		} else {
+			fmt.Fprint(w, "| -: ")
+		}
+	}
+	fmt.Fprintln(w, "|")
+	for _, l := range legs {
+		for _, c := range statsColumns {
+			v := formatValue(c.Value(l))
+			if f, ok := c.Value(l).(float64); ok {
+				// metres to the nearest metre, km and hours to 1 decimal place
+				places := 1
+				if strings.HasSuffix(c.Name, "_m") {
+					places = 0
+				}
+				v = strconv.FormatFloat(f, 'f', places, 64)
+			}
+			v = strings.Replace(v, "|", "\\|", -1)
+			fmt.Fprintf(w, "| %s ", v)
+		}
+		if _, err := fmt.Fprintln(w, "|"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package pipeline
+
+import (
+	"fmt"
+
+	"github.com/dave/ght/geojson"
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/project"
+)
+
+// trailGeoJSON returns a LineString feature for the route of each leg with its stats and ratings,
+// and Point features for its waypoints and passes. Properties have units in their names, like the
+// stats table, and the kind property tells routes, waypoints and passes apart for styling.
+func trailGeoJSON(t *project.Trail, legs []legRoute) *geojson.FeatureCollection {
+	c := geojson.New()
+	for _, leg := range legs {
+		l := leg.Notes
+		properties := map[string]interface{}{
+			"kind":      "route",
+			"leg":       l.Leg,
+			"name":      leg.Route.Name,
+			"from":      l.From,
+			"to":        l.To,
+			"length_km": l.Length,
+			"climb_m":   l.Climb,
+			"descent_m": l.Descent,
+			"top_m":     l.Top,
+			"bottom_m":  l.Bottom,
+			"notes":     l.Notes,
+		}
+		if l.Time > 0 {
+			properties["time_h"] = l.Time
+		}
+		for name, r := range map[string]notes.Rating{"route_rating": l.Route, "trail_rating": l.Trail, "lodge_rating": l.Quality} {
+			if r.Valid() {
+				properties[name] = int(r)
+			}
+		}
+		if l.Lodge.Valid() {
+			properties["lodge"] = notes.LodgeString(l.Lodge)
+		}
+		if s := t.SectionOf(l.Leg); s != nil {
+			properties["section"] = s.Name
+		}
+		c.AddLineString(gpx.Locations(leg.Route.Points), properties)
+
+		// passes are usually waypoints too
+		heights := map[string]float64{}
+		for _, p := range l.Passes {
+			heights[p.Pass] = p.Height
+		}
+		for _, w := range l.Waypoints {
+			properties := map[string]interface{}{
+				"kind":        "waypoint",
+				"leg":         l.Leg,
+				"name":        w.Name,
+				"elevation_m": w.Elevation,
+				"notes":       w.Notes,
+			}
+			if height, ok := heights[w.Name]; ok {
+				properties["kind"] = "pass"
+				properties["height_m"] = height
+				delete(heights, w.Name)
+			}
+			c.AddPoint(gpx.Point{Lat: w.Lat, Lon: w.Lon, Ele: w.Elevation}, properties)
+		}
+		for _, p := range l.Passes {
+			if _, ok := heights[p.Pass]; !ok {
+				continue
+			}
+			for _, w := range leg.Passes {
+				if w.Name != fmt.Sprintf("L%03d %s", l.Leg, p.Pass) {
+					continue
+				}
+				c.AddPoint(w.Point, map[string]interface{}{
+					"kind":     "pass",
+					"leg":      l.Leg,
+					"name":     p.Pass,
+					"height_m": p.Height,
+				})
+				break
+			}
+		}
+	}
+	return c
+}
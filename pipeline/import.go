@@ -0,0 +1,162 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/dave/ght/geo"
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/kml"
+	"github.com/dave/ght/project"
+)
+
+// ImportKML merges the routes and waypoints of a KML or KMZ file edited in Google Earth into the GPX
+// file of each leg, using the "L001 name" names of the files we write to find the leg. A leg's route
+// is replaced by the edited one, and waypoints are moved or added. Waypoints missing from the file
+// are reported but kept, because most edits only include the legs that changed.
+func ImportKML(t *project.Trail, opts Options) error {
+	if opts.Import == "" {
+		return fmt.Errorf("-kml must be the KML or KMZ file to import")
+	}
+	in, err := kml.Load(opts.Import)
+	if err != nil {
+		return err
+	}
+
+	routes, waypoints := routesByLeg(in), waypointsByLeg(in)
+	var legs []int
+	for leg := range routes {
+		legs = append(legs, leg)
+	}
+	for leg := range waypoints {
+		if _, ok := routes[leg]; !ok {
+			legs = append(legs, leg)
+		}
+	}
+	sort.Ints(legs)
+
+	files, err := ioutil.ReadDir(t.Gpx)
+	if err != nil {
+		return err
+	}
+	filenames := map[int]string{}
+	for _, f := range files {
+		if leg, ok := t.Leg(f.Name()); ok {
+			filenames[leg] = f.Name()
+		}
+	}
+
+	var failed LegErrors
+	for _, leg := range legs {
+		if !opts.Legs.Include(leg) {
+			continue
+		}
+		filename, ok := filenames[leg]
+		if !ok {
+			failed.Add(leg, fmt.Errorf("no GPX file for the leg in %s", t.Gpx))
+			continue
+		}
+		fpath := filepath.Join(t.Gpx, filename)
+		g, err := gpx.Load(fpath)
+		if err != nil {
+			failed.Add(leg, err)
+			continue
+		}
+
+		var changes []string
+		if r, ok := routes[leg]; ok {
+			old, err := g.RoutePoints()
+			if err != nil {
+				failed.Add(leg, err)
+				continue
+			}
+			if d, _ := deviation(gpx.Locations(old), gpx.Locations(r.Points)); d > 0 || len(old) != len(r.Points) {
+				keepElevations(old, r.Points)
+				setRoutePoints(&g, r.Points)
+				changes = append(changes, fmt.Sprintf("route of %d points (was %d), moved up to %.0f m", len(r.Points), len(old), d))
+			}
+		}
+
+		seen := map[string]bool{}
+		for _, w := range waypoints[leg] {
+			if r, ok := routes[leg]; ok && w.Name == r.Name {
+				// the waypoint with the leg's description in some export profiles
+				continue
+			}
+			seen[w.Name] = true
+			found := false
+			for i := range g.Waypoints {
+				existing := &g.Waypoints[i]
+				if existing.Name != w.Name {
+					continue
+				}
+				found = true
+				if d := geo.Distance(existing.Lat, existing.Lon, w.Lat, w.Lon) * 1000; d >= 1 {
+					changes = append(changes, fmt.Sprintf("waypoint %q moved %.0f m", w.Name, d))
+					existing.Lat, existing.Lon = w.Lat, w.Lon
+					if !w.NoEle && w.Ele != 0 {
+						existing.Ele, existing.NoEle = w.Ele, false
+					}
+				}
+				break
+			}
+			if !found {
+				changes = append(changes, fmt.Sprintf("waypoint %q added", w.Name))
+				g.Waypoints = append(g.Waypoints, gpx.Waypoint{Point: w.Point, Name: w.Name})
+			}
+		}
+		if len(waypoints[leg]) > 0 {
+			for _, w := range g.Waypoints {
+				if !seen[w.Name] {
+					changes = append(changes, fmt.Sprintf("waypoint %q not in the KML file, kept", w.Name))
+				}
+			}
+		}
+
+		if len(changes) == 0 {
+			continue
+		}
+		fmt.Printf("L%03d\n", leg)
+		for _, c := range changes {
+			fmt.Printf("  %s\n", c)
+		}
+		if !opts.skip(fpath) {
+			if err := gpx.Save(g, fpath); err != nil {
+				failed.Add(leg, err)
+			}
+		}
+	}
+	return failed.Err()
+}
+
+// keepElevations gives the points of an edited route with no elevation (Google Earth writes 0 for
+// clamped to ground lines) the elevation of the closest point of the old route. Run the dem command
+// to correct them.
+func keepElevations(old, edited []gpx.Waypoint) {
+	oldPoints := gpx.Locations(old)
+	for i := range edited {
+		if (edited[i].NoEle || edited[i].Ele == 0) && len(oldPoints) > 0 {
+			closest := oldPoints[gpx.Closest(oldPoints, edited[i].Point)]
+			edited[i].Ele, edited[i].NoEle = closest.Ele, closest.NoEle
+		}
+	}
+}
+
+// setRoutePoints replaces the route of a GPX file, keeping the name and description of the old one
+// and whether it was a route or a track.
+func setRoutePoints(g *gpx.GPX, points []gpx.Waypoint) {
+	switch {
+	case len(g.Routes) > 0:
+		r := g.Routes[0]
+		r.Points = points
+		g.Routes = []gpx.Route{r}
+	case len(g.Tracks) > 0:
+		trk := g.Tracks[0]
+		trk.Segments = []gpx.TrackSegment{{Points: points}}
+		g.Tracks = []gpx.Track{trk}
+	default:
+		g.Routes = []gpx.Route{{Points: points}}
+	}
+}
@@ -0,0 +1,149 @@
+package pipeline
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/kml"
+	"github.com/dave/ght/project"
+)
+
+// L001.gpx is in the style of Garmin BaseCamp and L002.gpx of gpx.studio, which writes tracks.
+var importFiles = map[string]string{
+	"ght.yaml": `trails:
+  - name: Great Himalaya Trail
+    slug: great-himalaya-trail
+    notes: trailnotes.json
+    gpx: gpx
+`,
+	"gpx/L001.gpx": `<?xml version="1.0" encoding="UTF-8"?>
+<gpx xmlns="http://www.topografix.com/GPX/1/1" xmlns:gpxx="http://www.garmin.com/xmlschemas/GpxExtensions/v3" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd" version="1.1" creator="Garmin Desktop App">
+	<metadata><name>L001</name></metadata>
+	<wpt lat="27.35" lon="87.67">
+		<ele>1820</ele>
+		<name>L001 Taplejung</name>
+		<sym>Flag, Blue</sym>
+		<extensions><gpxx:WaypointExtension><gpxx:DisplayMode>SymbolAndName</gpxx:DisplayMode></gpxx:WaypointExtension></extensions>
+	</wpt>
+	<wpt lat="27.4" lon="87.7"><ele>921</ele><name>L001 Mitlung</name></wpt>
+	<rte>
+		<name>L001 Taplejung to Mitlung</name>
+		<extensions><gpxx:RouteExtension><gpxx:DisplayColor>Magenta</gpxx:DisplayColor></gpxx:RouteExtension></extensions>
+		<rtept lat="27.35" lon="87.67"><ele>1820</ele></rtept>
+		<rtept lat="27.37" lon="87.68"><ele>0</ele></rtept>
+		<rtept lat="27.4" lon="87.7"><ele>921</ele></rtept>
+	</rte>
+</gpx>`,
+	"gpx/L002.gpx": `<?xml version="1.0" encoding="UTF-8"?>
+<gpx xmlns="http://www.topografix.com/GPX/1/1" version="1.1" creator="https://gpx.studio">
+	<wpt lat="27.4" lon="87.7"><ele>921</ele><name>L002 Mitlung</name></wpt>
+	<trk>
+		<name>L002 Mitlung to Chirwa</name>
+		<trkseg>
+			<trkpt lat="27.4" lon="87.7"><ele>921</ele></trkpt>
+			<trkpt lat="27.42" lon="87.72"><ele>1010</ele></trkpt>
+		</trkseg>
+		<trkseg>
+			<trkpt lat="27.45" lon="87.75"><ele>1270</ele></trkpt>
+		</trkseg>
+	</trk>
+</gpx>`,
+}
+
+func TestImportKML(t *testing.T) {
+	dir := t.TempDir()
+	for name, contents := range importFiles {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0666); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := project.Load(filepath.Join(dir, "ght.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// edited in Google Earth: Taplejung moved, and the route of L002 redrawn clamped to the ground
+	edited := gpx.GPX{
+		Waypoints: []gpx.Waypoint{
+			{Point: gpx.Point{Lat: 27.351, Lon: 87.671}, Name: "L001 Taplejung"},
+			{Point: gpx.Point{Lat: 27.4, Lon: 87.7, Ele: 921}, Name: "L001 Mitlung"},
+		},
+		Routes: []gpx.Route{{
+			Name: "L002 Mitlung to Chirwa",
+			Points: []gpx.Waypoint{
+				{Point: gpx.Point{Lat: 27.4, Lon: 87.7}},
+				{Point: gpx.Point{Lat: 27.43, Lon: 87.73}},
+				{Point: gpx.Point{Lat: 27.45, Lon: 87.75}},
+			},
+		}},
+	}
+	b, err := kml.Marshal(kml.FromGpx(edited, "Edits"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := filepath.Join(dir, "edits.kml")
+	if err := ioutil.WriteFile(in, b, 0666); err != nil {
+		t.Fatal(err)
+	}
+	if err := ImportKML(p.Trails[0], Options{Import: in}); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string][]string{
+		"L001.gpx": {
+			`xmlns:gpxx="http://www.garmin.com/xmlschemas/GpxExtensions/v3"`,
+			`xsi:schemaLocation="http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd"`,
+			`creator="Garmin Desktop App"`,
+			`<wpt lat="27.351" lon="87.671">`,
+			`<sym>Flag, Blue</sym>`,
+			`<gpxx:DisplayMode>SymbolAndName</gpxx:DisplayMode>`,
+			`<gpxx:DisplayColor>Magenta</gpxx:DisplayColor>`,
+			"<rtept lat=\"27.37\" lon=\"87.68\">\n\t\t\t<ele>0</ele>",
+		},
+		"L002.gpx": {
+			`creator="https://gpx.studio"`,
+			`<name>L002 Mitlung to Chirwa</name>`,
+			"<trkpt lat=\"27.43\" lon=\"87.73\">\n\t\t\t\t<ele>1010</ele>", // closest old elevation
+		},
+	} {
+		b, err := ioutil.ReadFile(filepath.Join(dir, "gpx", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := string(b)
+		if !strings.HasPrefix(s, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<gpx ") || !strings.HasSuffix(s, "</gpx>") {
+			t.Errorf("%s: root element is not gpx:\n%s", name, s)
+		}
+		if n := strings.Count(s, `xmlns="`); n != 1 {
+			t.Errorf("%s: found %d default namespace declarations, want 1", name, n)
+		}
+		for _, w := range want {
+			if !strings.Contains(s, w) {
+				t.Errorf("%s: no %q in:\n%s", name, w, s)
+			}
+		}
+
+		// and it's still a file we can read
+		g, err := gpx.Load(filepath.Join(dir, "gpx", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		points, err := g.Points()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != 3 {
+			t.Errorf("%s: got %d points, want 3", name, len(points))
+		}
+		if name == "L002.gpx" && (len(g.Routes) != 0 || len(g.Tracks) != 1 || len(g.Tracks[0].Segments) != 1) {
+			t.Errorf("%s: the edited route should be the one segment of the track", name)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package pipeline
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dave/ght/kml"
+	"github.com/dave/ght/project"
+)
+
+// WriteKMZ writes routes-vN.kmz, with the map and elevation chart of each leg in the zip and shown
+// in the description of its route, so Google Earth has the whole of the trail notes offline. Run maps
+// and elevations first: legs without images are described by their notes alone.
+func WriteKMZ(t *project.Trail, opts Options) error {
+	outDir := t.Output.Routes
+	if err := opts.mkdir("output.routes", outDir); err != nil {
+		return err
+	}
+	// legs that fail are left out and reported at the end
+	legs, failed := legRoutes(t, nil, opts)
+	if len(legs) == 0 && failed != nil {
+		return failed
+	}
+
+	files := map[string][]byte{}
+	image := func(dir, name string) string {
+		if dir == "" {
+			return ""
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			return ""
+		}
+		files["images/"+name] = b
+		return fmt.Sprintf(`<img src="images/%s" width="600"><br>`, name)
+	}
+	var missing int
+	for i := range legs {
+		leg := legs[i].Notes
+		var desc strings.Builder
+		for _, p := range strings.Split(strings.TrimSpace(leg.Notes), "\n") {
+			if p = strings.TrimSpace(p); p != "" {
+				fmt.Fprintf(&desc, "<p>%s</p>", html.EscapeString(p))
+			}
+		}
+		fmt.Fprintf(&desc, "<p>%.1f km, %.0f m climb, %.0f m descent", leg.Length, leg.Climb, leg.Descent)
+		if leg.Time > 0 {
+			fmt.Fprintf(&desc, ", about %.1f hours walking", leg.Time)
+		}
+		desc.WriteString("</p>")
+		for _, img := range []string{
+			image(t.Output.Maps, fmt.Sprintf("L%03d.jpg", leg.Leg)),
+			image(t.Output.Elevations, fmt.Sprintf("E%03d.png", leg.Leg)),
+		} {
+			if img == "" {
+				missing++
+			}
+			desc.WriteString(img)
+		}
+		legs[i].Route.Desc = desc.String()
+	}
+	if missing > 0 {
+		fmt.Fprintf(os.Stderr, "%d map and elevation images not found, run \"ght maps\" and \"ght elevations\" to include them\n", missing)
+	}
+
+	k := trailKML(t, t.Name, legs, t.KML.ColorBy)
+	if fpath := filepath.Join(outDir, fmt.Sprintf("routes-v%v.kmz", opts.Version)); !opts.skip(fpath) {
+		if err := kml.SaveKMZ(k, files, fpath); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%d legs, %d images\n", len(legs), len(files))
+	return failed
+}
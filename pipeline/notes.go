@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/project"
+	"github.com/dave/ght/render"
+)
+
+func CreateTrailNotes(t *project.Trail, opts Options) error {
+	alg, err := opts.climb(t)
+	if err != nil {
+		return err
+	}
+	model, err := opts.walking(t)
+	if err != nil {
+		return err
+	}
+	sheet, err := notes.Load(t.Notes, t.Start)
+	if err != nil {
+		return err
+	}
+	var legs []*notes.Leg
+	for _, leg := range sheet.Legs {
+		if !opts.Legs.Include(leg.Leg) {
+			continue
+		}
+		legs = append(legs, leg)
+	}
+	headings := map[int]*project.Section{}
+	done := map[*project.Section]bool{}
+	for _, leg := range legs {
+		if s := t.SectionOf(leg.Leg); s != nil && !done[s] {
+			headings[leg.Leg] = s
+			done[s] = true
+		}
+	}
+	var sections []notes.Totals
+	for _, s := range t.Sections {
+		var legs []*notes.Leg
+		for _, leg := range sheet.Legs {
+			if s.Includes(leg.Leg) {
+				legs = append(legs, leg)
+			}
+		}
+		sections = append(sections, notes.Total(s.Name, legs))
+	}
+	if err := opts.mkdir("content", t.Content); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+
+	data := render.TrailNotesData{
+		Maps:          true,
+		Legs:          legs,
+		Version:       opts.Version,
+		MapsURL:       imageURL(t, t.Output.Maps),
+		ElevationsURL: imageURL(t, t.Output.Elevations),
+		Trail:         t,
+		Climb:         alg.Description,
+		Walking:       model.Description,
+		Total:         notes.Total(t.Name, sheet.Legs),
+		Sections:      sections,
+		Headings:      headings,
+	}
+
+	if err := render.TrailNotes(&out, data); err != nil {
+		return err
+	}
+	if fpath := filepath.Join(t.Content, "trail-notes.en.md"); !opts.skip(fpath) {
+		if err := ioutil.WriteFile(fpath, out.Bytes(), 0777); err != nil {
+			return err
+		}
+	}
+
+	out = bytes.Buffer{}
+	data.Maps = false
+	if err := render.TrailNotes(&out, data); err != nil {
+		return err
+	}
+	if fpath := filepath.Join(t.Content, "trail-notes-no-maps.en.md"); !opts.skip(fpath) {
+		if err := ioutil.WriteFile(fpath, out.Bytes(), 0777); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// imageURL returns the URL the images in an output directory are served from: they're uploaded to a
+// directory of the same name at the trail's image_url.
+func imageURL(t *project.Trail, dir string) string {
+	return strings.TrimSuffix(t.ImageURL, "/") + "/" + filepath.Base(dir)
+}
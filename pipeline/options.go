@@ -0,0 +1,124 @@
+// Package pipeline has the stages that turn the trail notes and GPX files of a trail into routes,
+// maps, elevation profiles and trail notes pages.
+package pipeline
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dave/ght/project"
+	"github.com/dave/ght/stats"
+)
+
+// Options holds the flags shared by all commands.
+type Options struct {
+	Version int
+	Legs    LegSet
+	DryRun  bool
+	Sheet   bool   // write to the google sheet as well as the trail notes
+	Climb   string // climb algorithm, overriding the trail's climb setting
+	Walking string // walking time model, overriding the trail's walking setting
+	Format  string // output format of the stats table
+
+	Since     int     // version the diff command compares with, default the previous version
+	OldNotes  string  // trail notes snapshot the diff command compares with
+	Threshold float64 // distance in m a route or waypoint must move to be reported by the diff command
+
+	Import string // KML or KMZ file the import command merges into the GPX files
+}
+
+// climb returns the climb algorithm to use for a trail.
+func (o Options) climb(t *project.Trail) (stats.Algorithm, error) {
+	if o.Climb != "" {
+		return stats.Lookup(o.Climb)
+	}
+	return stats.Lookup(t.Climb)
+}
+
+// walking returns the walking time model to use for a trail.
+func (o Options) walking(t *project.Trail) (stats.TimeModel, error) {
+	if o.Walking != "" {
+		return stats.LookupTimeModel(o.Walking)
+	}
+	return stats.LookupTimeModel(t.Walking)
+}
+
+// mkdir creates an output directory, unless we're in dry-run mode.
+func (o Options) mkdir(name, dir string) error {
+	if dir == "" {
+		return fmt.Errorf("%s is not set in the config file", name)
+	}
+	if o.DryRun {
+		return nil
+	}
+	return os.MkdirAll(dir, 0777)
+}
+
+// skip reports whether writing filename should be skipped because we're in dry-run mode.
+func (o Options) skip(filename string) bool {
+	if o.DryRun {
+		fmt.Fprintf(os.Stderr, "dry run: skipping write of %s\n", filename)
+	}
+	return o.DryRun
+}
+
+// LegSet is a set of leg numbers parsed from a list of legs and ranges, e.g. "1,5,40-52". An empty
+// set includes every leg.
+type LegSet map[int]bool
+
+func (s LegSet) Include(leg int) bool {
+	return len(s) == 0 || s[leg]
+}
+
+func (s LegSet) String() string {
+	var legs []int
+	for leg := range s {
+		legs = append(legs, leg)
+	}
+	sort.Ints(legs)
+	var parts []string
+	for i := 0; i < len(legs); i++ {
+		j := i
+		for j+1 < len(legs) && legs[j+1] == legs[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", legs[i], legs[j]))
+		} else {
+			parts = append(parts, strconv.Itoa(legs[i]))
+		}
+		i = j
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s LegSet) Set(value string) error {
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return fmt.Errorf("invalid leg %q", part)
+		}
+		last := first
+		if isRange {
+			last, err = strconv.Atoi(strings.TrimSpace(to))
+			if err != nil {
+				return fmt.Errorf("invalid leg range %q", part)
+			}
+		}
+		if last < first {
+			return fmt.Errorf("invalid leg range %q", part)
+		}
+		for leg := first; leg <= last; leg++ {
+			s[leg] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package pipeline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLegSet(t *testing.T) {
+	for _, test := range []struct {
+		value  string
+		legs   []int
+		string string
+	}{
+		{"", nil, ""},
+		{"5", []int{5}, "5"},
+		{"1,5,40-43", []int{1, 5, 40, 41, 42, 43}, "1,5,40-43"},
+		{" 3 - 4 , 2", []int{2, 3, 4}, "2-4"},
+		{"7,7-8,", []int{7, 8}, "7-8"},
+	} {
+		s := LegSet{}
+		if err := s.Set(test.value); err != nil {
+			t.Errorf("%q: %v", test.value, err)
+			continue
+		}
+		var legs []int
+		for leg := 1; leg <= 50; leg++ {
+			if s[leg] {
+				legs = append(legs, leg)
+			}
+		}
+		if !reflect.DeepEqual(legs, test.legs) {
+			t.Errorf("%q: got %v, want %v", test.value, legs, test.legs)
+		}
+		if s.String() != test.string {
+			t.Errorf("%q: got %q, want %q", test.value, s.String(), test.string)
+		}
+	}
+
+	for _, value := range []string{"x", "5-", "-5", "9-3", "1-x"} {
+		if err := (LegSet{}).Set(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+
+	if s := (LegSet{}); !s.Include(12) {
+		t.Error("an empty set should include every leg")
+	}
+	if s := (LegSet{3: true}); s.Include(12) || !s.Include(3) {
+		t.Error("a set should only include its legs")
+	}
+}
@@ -0,0 +1,137 @@
+package pipeline
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/kml"
+	"github.com/dave/ght/project"
+)
+
+// Profile is an app the routes are exported for, with the quirks of the files it reads.
+type Profile struct {
+	Name   string // in file names, e.g. routes-for-maps-me-v11.kml
+	App    string
+	Format string // "gpx" or "kml"
+
+	// StartWaypoint moves the description of each leg from its route to a waypoint at the start of the
+	// leg, for apps that don't show route descriptions.
+	StartWaypoint bool
+	Tracks        bool   // write tracks instead of routes, for apps that recalculate or hide routes
+	Symbol        string // symbol of the waypoints, in the app's own names
+	Colors        string // how each leg gets its own colour: "kml" styles (by leg unless the trail sets kml color_by), or "garmin" or "osmand" GPX extensions, or "" for none
+}
+
+// Profiles are the apps that can be listed in the profiles setting of a trail. Each gets a routes file
+// of its own.
+var Profiles = []Profile{
+	{Name: "maps-me", App: "maps.me", Format: "kml", StartWaypoint: true},
+	{Name: "organic-maps", App: "Organic Maps", Format: "kml", StartWaypoint: true, Colors: "kml"},
+	{Name: "osmand", App: "OsmAnd", Format: "gpx", Tracks: true, Colors: "osmand"},
+	{Name: "gaia-gps", App: "Gaia GPS", Format: "gpx", Tracks: true},
+	{Name: "basecamp", App: "Garmin BaseCamp", Format: "gpx", Tracks: true, Symbol: "Flag, Blue", Colors: "garmin"},
+	{Name: "caltopo", App: "CalTopo", Format: "kml", Colors: "kml"},
+}
+
+// LookupProfile returns the export profile with the given name.
+func LookupProfile(name string) (*Profile, error) {
+	var names []string
+	for i, p := range Profiles {
+		if p.Name == name {
+			return &Profiles[i], nil
+		}
+		names = append(names, p.Name)
+	}
+	return nil, fmt.Errorf("unknown export profile %q (choose from %s)", name, strings.Join(names, ", "))
+}
+
+// profiles returns the export profiles of a trail. maps.me is the default, because its file has
+// always been written.
+func profiles(t *project.Trail) ([]*Profile, error) {
+	names := t.Profiles
+	if names == nil {
+		names = []string{"maps-me"}
+	}
+	var out []*Profile
+	for _, name := range names {
+		p, err := LookupProfile(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+// Filename returns the name of the profile's routes file.
+func (p *Profile) Filename(version int) string {
+	return fmt.Sprintf("routes-for-%s-v%v.%s", p.Name, version, p.Format)
+}
+
+// palette is the colours of the legs in the GPX extensions, as Garmin display colours and hex.
+var palette = []struct{ Garmin, Hex string }{
+	{"Red", "#ff0014"},
+	{"Green", "#00ff78"},
+	{"Blue", "#0078ff"},
+	{"Cyan", "#14fff0"},
+	{"DarkYellow", "#ff7814"},
+	{"DarkGreen", "#148c00"},
+	{"Magenta", "#7878ff"},
+	{"DarkRed", "#963c14"},
+	{"DarkBlue", "#1414f0"},
+}
+
+// write writes the routes file of the profile.
+func (p *Profile) write(fpath string, t *project.Trail, legs []legRoute, g gpx.GPX) error {
+	if p.Format == "kml" {
+		colorBy := t.KML.ColorBy
+		if colorBy == "" && p.Colors == "kml" {
+			colorBy = "leg"
+		}
+		return kml.Save(trailKML(t, t.Name, legs, colorBy), fpath)
+	}
+
+	for i := range g.Waypoints {
+		if g.Waypoints[i].Sym == "" {
+			g.Waypoints[i].Sym = p.Symbol
+		}
+	}
+	extension := func(i int) *gpx.Extensions {
+		c := palette[i%len(palette)]
+		switch p.Colors {
+		case "garmin":
+			element := "gpxx:RouteExtension"
+			if p.Tracks {
+				element = "gpxx:TrackExtension"
+			}
+			return &gpx.Extensions{XML: fmt.Sprintf("<%s><gpxx:DisplayColor>%s</gpxx:DisplayColor></%s>", element, c.Garmin, element)}
+		case "osmand":
+			return &gpx.Extensions{XML: fmt.Sprintf("<osmand:color>%s</osmand:color>", c.Hex)}
+		}
+		return nil
+	}
+	switch p.Colors {
+	case "garmin":
+		g.Attrs = append(g.Attrs, xml.Attr{Name: xml.Name{Local: "xmlns:gpxx"}, Value: "http://www.garmin.com/xmlschemas/GpxExtensions/v3"})
+	case "osmand":
+		g.Attrs = append(g.Attrs, xml.Attr{Name: xml.Name{Local: "xmlns:osmand"}, Value: "https://osmand.net"})
+	}
+	routes := g.Routes
+	g.Routes = nil
+	for i, r := range routes {
+		if !p.Tracks {
+			r.Extensions = extension(i)
+			g.Routes = append(g.Routes, r)
+			continue
+		}
+		g.Tracks = append(g.Tracks, gpx.Track{
+			Name:       r.Name,
+			Desc:       r.Desc,
+			Extensions: extension(i),
+			Segments:   []gpx.TrackSegment{{Points: r.Points}},
+		})
+	}
+	return gpx.Save(g, fpath)
+}
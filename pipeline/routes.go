@@ -0,0 +1,277 @@
+package pipeline
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dave/ght/geojson"
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/kml"
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/project"
+)
+
+// RunAll runs every stage, carrying on after a stage fails so one bad leg doesn't stop the files of
+// the other legs being written, and fails at the end if any stage failed.
+func RunAll(t *project.Trail, opts Options) error {
+	var failed []string
+	for _, stage := range []struct {
+		Name string
+		Run  func(*project.Trail, Options) error
+	}{
+		{"routes", ProcessFinalRoutesAll},
+		{"downloads", WriteDownloads},
+		{"routes-page", CreateRoutesPage},
+		{"notes", CreateTrailNotes},
+		{"maps", DrawMaps},
+		{"elevations", DrawElevations},
+		{"kmz", WriteKMZ},
+	} {
+		if err := stage.Run(t, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "%s failed: %v\n", stage.Name, err)
+			failed = append(failed, stage.Name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s failed", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// ProcessFinalRoutesAll writes the routes files, then a routes file for each export profile of the
+// trail.
+func ProcessFinalRoutesAll(t *project.Trail, opts Options) error {
+	profiles, err := profiles(t)
+	if err != nil {
+		return err
+	}
+	if err := ProcessFinalRoutes(t, nil, opts); err != nil {
+		return err
+	}
+	for _, p := range profiles {
+		if err := ProcessFinalRoutes(t, p, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ProcessFinalRoutes writes the routes file of an export profile, or if profile is nil, the GPX and
+// KML files of the whole trail and its GeoJSON file for web maps. The files of each leg and section
+// are written by WriteDownloads.
+func ProcessFinalRoutes(t *project.Trail, profile *Profile, opts Options) error {
+
+	outDir := t.Output.Routes
+	if err := opts.mkdir("output.routes", outDir); err != nil {
+		return err
+	}
+
+	legs, err := legRoutes(t, profile, opts)
+	if err != nil {
+		// the routes files are never written with legs missing
+		return err
+	}
+
+	out := gpx.GPX{
+		Xmlns:   gpx.Namespace,
+		Version: "1.1",
+		Creator: "ght",
+		Metadata: &gpx.Metadata{
+			Name: t.Name,
+		},
+	}
+
+	for _, leg := range legs {
+		out.Routes = append(out.Routes, leg.Route)
+		out.Waypoints = append(out.Waypoints, leg.Waypoints...)
+	}
+
+	if profile != nil {
+		if fpath := filepath.Join(outDir, profile.Filename(opts.Version)); !opts.skip(fpath) {
+			if err := profile.write(fpath, t, legs, out); err != nil {
+				return err
+			}
+		}
+	} else {
+		if fpath := filepath.Join(outDir, fmt.Sprintf("routes-v%v.gpx", opts.Version)); !opts.skip(fpath) {
+			if err := gpx.Save(out, fpath); err != nil {
+				return err
+			}
+		}
+		if fpath := filepath.Join(outDir, fmt.Sprintf("routes-v%v.kml", opts.Version)); !opts.skip(fpath) {
+			if err := kml.Save(trailKML(t, t.Name, legs, t.KML.ColorBy), fpath); err != nil {
+				return err
+			}
+		}
+		if fpath := filepath.Join(outDir, fmt.Sprintf("routes-v%v.geojson", opts.Version)); !opts.skip(fpath) {
+			if err := geojson.Save(trailGeoJSON(t, legs), fpath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+
+}
+
+// kmlSections returns the routes and waypoints of each section of the trail, then of the legs that
+// aren't in a section.
+func kmlSections(t *project.Trail, legs []legRoute) []kml.Section {
+	sections := make([]kml.Section, len(t.Sections)+1)
+	for i, s := range t.Sections {
+		sections[i] = kml.Section{Name: s.Name, Description: s.Description}
+	}
+	sectionOf := func(leg int) *kml.Section {
+		for i, s := range t.Sections {
+			if s.Includes(leg) {
+				return &sections[i]
+			}
+		}
+		return &sections[len(t.Sections)]
+	}
+	for _, leg := range legs {
+		section := sectionOf(leg.Leg)
+		section.Routes = append(section.Routes, leg.Route)
+		section.Waypoints = append(section.Waypoints, leg.Waypoints...)
+	}
+	return sections
+}
+
+// failedLegs returns the legs in a LegErrors or WaypointReport error from legRoutes.
+func failedLegs(err error) map[int]bool {
+	legs := map[int]bool{}
+	switch err := err.(type) {
+	case LegErrors:
+		for _, e := range err {
+			legs[e.Leg] = true
+		}
+	case WaypointReport:
+		for _, p := range err {
+			legs[p.Leg] = true
+		}
+	}
+	return legs
+}
+
+// legRoute is the route and waypoints of a leg, ready to be written to the routes files.
+type legRoute struct {
+	Leg       int
+	Notes     *notes.Leg
+	Route     gpx.Route
+	Waypoints []gpx.Waypoint
+	Passes    []gpx.Waypoint // from the GPX file, described by their height
+}
+
+// legRoutes reads the GPX file of each leg and names and describes its route and waypoints from the
+// trail notes, with the quirks of the export profile if it's not nil. If some legs can't be
+// processed, it returns the rest with a LegErrors or WaypointReport error for the ones that failed,
+// so per-leg files can still be written. If nothing can be read, it returns no legs.
+func legRoutes(t *project.Trail, profile *Profile, opts Options) ([]legRoute, error) {
+
+	sheet, err := notes.Load(t.Notes, t.Start)
+	if err != nil {
+		return nil, err
+	}
+
+	legsByLeg := map[int]*notes.Leg{}
+	for _, leg := range sheet.Legs {
+		legsByLeg[leg.Leg] = leg
+	}
+
+	inDir := t.Gpx
+	routeFiles, err := ioutil.ReadDir(inDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var legs []legRoute
+	var failed LegErrors
+	var report WaypointReport
+	for _, fileInfo := range routeFiles {
+		legNumber, ok := t.Leg(fileInfo.Name())
+		if !ok || !opts.Legs.Include(legNumber) {
+			continue
+		}
+		g, err := gpx.Load(filepath.Join(inDir, fileInfo.Name()))
+		if err != nil {
+			failed.Add(legNumber, err)
+			continue
+		}
+
+		leg := legsByLeg[legNumber]
+		if leg == nil {
+			failed.Add(legNumber, fmt.Errorf("leg not found in trail notes"))
+			continue
+		}
+
+		if problems := checkWaypoints(leg, g); len(problems) > 0 {
+			report = append(report, problems...)
+			continue
+		}
+
+		startWaypoint := profile != nil && profile.StartWaypoint
+		routeDesc := leg.Notes
+		if startWaypoint {
+			routeDesc = ""
+		}
+		points, err := g.RoutePoints()
+		if err != nil {
+			failed.Add(legNumber, err)
+			continue
+		}
+		route := gpx.Route{
+			Name:   fmt.Sprintf("L%03d %s to %s", leg.Leg, leg.From, leg.To),
+			Desc:   routeDesc,
+			Points: points,
+		}
+		var waypoints []gpx.Waypoint
+		if startWaypoint {
+			// maps.me doesn't show descriptions for routes so we add a dummy waypoint and remove the route desc
+
+			waypoints = append(waypoints, gpx.Waypoint{
+				Point: points[0].Point,
+				Name:  fmt.Sprintf("L%03d %s to %s", leg.Leg, leg.From, leg.To),
+				Desc:  leg.Notes,
+			})
+		}
+		for _, w := range leg.Waypoints {
+			waypoints = append(waypoints, gpx.Waypoint{
+				Point: gpx.Point{
+					Lat: w.Lat,
+					Lon: w.Lon,
+					Ele: w.Elevation,
+				},
+				Name: fmt.Sprintf("L%03d %s", leg.Leg, w.Name),
+				Desc: w.Notes,
+			})
+		}
+		var passes []gpx.Waypoint
+		for _, p := range leg.Passes {
+			for _, w := range g.Waypoints {
+				if w.Name == fmt.Sprintf("L%03d %s", leg.Leg, p.Pass) {
+					passes = append(passes, gpx.Waypoint{Point: w.Point, Name: w.Name, Desc: fmt.Sprintf("%s, %.0f m", p.Pass, p.Height)})
+				}
+			}
+		}
+		legs = append(legs, legRoute{Leg: legNumber, Notes: leg, Route: route, Waypoints: waypoints, Passes: passes})
+	}
+
+	if len(report) > 0 {
+		report.Print(os.Stdout)
+		if len(failed) == 0 {
+			return legs, report
+		}
+		// the report has been printed, but the legs in it failed too
+		reported := map[int]bool{}
+		for _, p := range report {
+			if !reported[p.Leg] {
+				reported[p.Leg] = true
+				failed = append(failed, LegError{Leg: p.Leg, Err: fmt.Errorf("waypoint problems")})
+			}
+		}
+	}
+	return legs, failed.Err()
+}
@@ -0,0 +1,107 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dave/ght/project"
+	"github.com/dave/ght/render"
+)
+
+// CreateRoutesPage writes the GPS routes page, linking to the routes files of the version and the
+// per-leg and per-section files in its downloads manifest, with their sizes and the changelog of
+// every release. Run routes and downloads first.
+func CreateRoutesPage(t *project.Trail, opts Options) error {
+	release := t.Release(opts.Version)
+	if release == nil {
+		return fmt.Errorf("no release of version %d in the config file", opts.Version)
+	}
+	if t.RoutesURL == "" {
+		return fmt.Errorf("routes_url is not set in the config file")
+	}
+	if t.Output.Routes == "" {
+		return fmt.Errorf("output.routes is not set in the config file")
+	}
+	url := func(file string) string {
+		return strings.TrimSuffix(t.RoutesURL, "/") + "/" + file
+	}
+
+	data := render.GPSRoutesData{
+		Trail:   t,
+		Release: release,
+	}
+	profiles, err := profiles(t)
+	if err != nil {
+		return err
+	}
+	files := []struct{ Label, File string }{
+		{"GPX", fmt.Sprintf("routes-v%v.gpx", opts.Version)},
+		{"KML", fmt.Sprintf("routes-v%v.kml", opts.Version)},
+		{"GeoJSON", fmt.Sprintf("routes-v%v.geojson", opts.Version)},
+	}
+	for _, p := range profiles {
+		files = append(files, struct{ Label, File string }{p.App + " " + strings.ToUpper(p.Format), p.Filename(opts.Version)})
+	}
+	for _, f := range files {
+		file := f.File
+		info, err := os.Stat(filepath.Join(t.Output.Routes, file))
+		if err != nil {
+			return fmt.Errorf("%w (run \"ght routes -version %d\" first)", err, opts.Version)
+		}
+		data.Whole = append(data.Whole, render.RouteFile{Label: f.Label, URL: url(file), Size: int(info.Size())})
+	}
+
+	if t.Output.Downloads != "" {
+		b, err := ioutil.ReadFile(filepath.Join(t.Output.Downloads, fmt.Sprintf("manifest-v%v.json", opts.Version)))
+		if err != nil {
+			return fmt.Errorf("%w (run \"ght downloads -version %d\" first)", err, opts.Version)
+		}
+		var manifest Manifest
+		if err := json.Unmarshal(b, &manifest); err != nil {
+			return fmt.Errorf("error decoding downloads manifest: %w", err)
+		}
+		// the files are in pairs, one of each format, for each leg or section
+		var last *render.RouteDownload
+		for _, d := range manifest.Files {
+			list, name := &data.Legs, d.Name
+			if d.Section != "" {
+				list = &data.Sections
+				for _, s := range t.Sections {
+					if s.Slug() == d.Section {
+						name = s.Name
+					}
+				}
+			}
+			if last == nil || last.Name != name {
+				*list = append(*list, render.RouteDownload{Name: name})
+				last = &(*list)[len(*list)-1]
+			}
+			last.Files = append(last.Files, render.RouteFile{Label: strings.ToUpper(d.Format), URL: url(d.File), Size: d.Size})
+		}
+	}
+
+	data.Releases = append(data.Releases, t.Releases...)
+	sort.SliceStable(data.Releases, func(i, j int) bool {
+		return data.Releases[i].Version > data.Releases[j].Version
+	})
+
+	var out bytes.Buffer
+	if err := render.GPSRoutes(&out, data); err != nil {
+		return err
+	}
+	if err := opts.mkdir("content", t.Content); err != nil {
+		return err
+	}
+	if fpath := filepath.Join(t.Content, "gps-routes.en.md"); !opts.skip(fpath) {
+		if err := ioutil.WriteFile(fpath, out.Bytes(), 0777); err != nil {
+			return err
+		}
+	}
+	return nil
+}
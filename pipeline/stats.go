@@ -0,0 +1,165 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/project"
+	"github.com/dave/ght/sheets"
+	"github.com/dave/ght/stats"
+)
+
+// CalcStats calculates the stats of each leg from the GPX files and writes them to the Legs of the
+// trail notes, and the Legs tab of the google sheet with -sheet. The climb algorithm and the changes
+// for each leg are printed, and with -format the stats of every leg are written to stdout as a table
+// and everything else goes to stderr.
+func CalcStats(t *project.Trail, opts Options) error {
+	log := os.Stdout
+	if opts.Format != "" {
+		log = os.Stderr
+		if _, ok := statsFormats[opts.Format]; !ok {
+			return fmt.Errorf("unknown format %q (choose from csv, json or markdown)", opts.Format)
+		}
+	}
+	alg, err := opts.climb(t)
+	if err != nil {
+		return err
+	}
+	model, err := opts.walking(t)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(log, "climb: %s\n", alg)
+	fmt.Fprintf(log, "walking: %s\n", model)
+
+	sheet, err := notes.Load(t.Notes, t.Start)
+	if err != nil {
+		return err
+	}
+
+	routesDir := t.Gpx
+	routeFiles, err := ioutil.ReadDir(routesDir)
+	if err != nil {
+		return err
+	}
+
+	var failed LegErrors
+	legStats := map[int]stats.Stats{}
+	var legs []int
+	for _, fileInfo := range routeFiles {
+
+		leg, ok := t.Leg(fileInfo.Name())
+		if !ok || !opts.Legs.Include(leg) {
+			continue
+		}
+
+		g, err := gpx.Load(filepath.Join(routesDir, fileInfo.Name()))
+		if err != nil {
+			failed.Add(leg, err)
+			continue
+		}
+		if len(g.Routes)+len(g.Tracks) != 1 {
+			failed.Add(leg, fmt.Errorf("not 1 route / track for %q", fileInfo.Name()))
+			continue
+		}
+
+		points, err := g.Points()
+		if err != nil {
+			failed.Add(leg, err)
+			continue
+		}
+
+		if sheet.Leg(leg) == nil {
+			failed.Add(leg, fmt.Errorf("leg not found in trail notes"))
+			continue
+		}
+		legStats[leg] = stats.Calc(points, alg, model)
+		legs = append(legs, leg)
+	}
+	sort.Ints(legs)
+
+	updates := map[int]map[string]interface{}{}
+	for _, leg := range legs {
+		l := sheet.Leg(leg)
+		s := legStats[leg]
+		var changes []string
+		values := map[string]interface{}{}
+		for _, f := range []struct {
+			Name     string
+			Old, New *float64
+			Places   float64
+		}{
+			// the sheet has 6 decimal places, and times are in hours to 2 decimal places
+			{"Length", &l.Length, &s.Length, 1e6},
+			{"Climb", &l.Climb, &s.Climb, 1e6},
+			{"Descent", &l.Descent, &s.Descent, 1e6},
+			{"Start", &l.Start, &s.Start, 1e6},
+			{"End", &l.End, &s.End, 1e6},
+			{"Top", &l.Top, &s.Top, 1e6},
+			{"Bottom", &l.Bottom, &s.Bottom, 1e6},
+			{"Time", &l.Time, &s.Time, 100},
+		} {
+			value := math.Round(*f.New*f.Places) / f.Places
+			if value == *f.Old {
+				continue
+			}
+			changes = append(changes, fmt.Sprintf("%s %s -> %s", f.Name, formatFloat(*f.Old), formatFloat(value)))
+			values[f.Name] = value
+			*f.Old = value
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		fmt.Fprintf(log, "L%03d %s\n", leg, strings.Join(changes, ", "))
+		updates[leg] = values
+	}
+	if len(updates) == 0 {
+		fmt.Fprintln(log, "no changes")
+	}
+
+	if opts.Format != "" {
+		var rows []*notes.Leg
+		for _, leg := range legs {
+			rows = append(rows, sheet.Leg(leg))
+		}
+		if err := statsFormats[opts.Format](os.Stdout, rows, alg, model); err != nil {
+			return err
+		}
+	}
+
+	if len(updates) > 0 && !opts.skip(t.Notes) {
+		if err := sheet.Save(t.Notes); err != nil {
+			return err
+		}
+	}
+	if len(updates) > 0 && opts.Sheet {
+		if opts.DryRun {
+			fmt.Fprintln(os.Stderr, "dry run: skipping write of google sheet")
+		} else {
+			ctx := context.Background()
+			c, err := sheets.New(ctx, t)
+			if err != nil {
+				return err
+			}
+			// the sheet may not have a Time column, the walking times are in the trail notes anyway
+			if err := c.UpdateLegs(ctx, updates, "Time"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return failed.Err()
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,159 @@
+package pipeline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/kml"
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/project"
+)
+
+// defaultIcons are the Google Earth icons of each waypoint category.
+var defaultIcons = map[string]string{
+	"start":      "http://maps.google.com/mapfiles/kml/shapes/trail.png",
+	"pass":       "http://maps.google.com/mapfiles/kml/shapes/mountains.png",
+	"campsite":   "http://maps.google.com/mapfiles/kml/shapes/campground.png",
+	"guesthouse": "http://maps.google.com/mapfiles/kml/shapes/lodging.png",
+	"waypoint":   "http://maps.google.com/mapfiles/kml/shapes/placemark_circle.png",
+}
+
+// defaultBalloon shows the stats of a leg above its description.
+const defaultBalloon = `<h3>$[name]</h3>
+<table>
+<tr><td>Length</td><td>$[length] km</td></tr>
+<tr><td>Climb / descent</td><td>$[climb] m / $[descent] m</td></tr>
+<tr><td>Walking time</td><td>$[time]</td></tr>
+<tr><td>Accommodation</td><td>$[lodge]</td></tr>
+</table>
+$[description]`
+
+// lodgeColors are the route colours of each type of accommodation at the end of the leg.
+var lodgeColors = map[notes.Lodge]string{
+	notes.Campsite:   "dark_green",
+	notes.Shelter:    "brown",
+	notes.Homestay:   "purple",
+	notes.Guesthouse: "blue",
+}
+
+// difficulty grades a leg easy, moderate or hard from its walking time and climb, and returns the
+// colour of the grade.
+func difficulty(leg *notes.Leg) string {
+	switch {
+	case leg.Time < 5 && leg.Climb < 800:
+		return "green"
+	case leg.Time < 8 && leg.Climb < 1500:
+		return "orange"
+	default:
+		return "red"
+	}
+}
+
+// trailKML converts the legs to a KML document called name, with a folder for each section and the
+// trail's styling.
+func trailKML(t *project.Trail, name string, legs []legRoute, colorBy string) kml.KML {
+	return kml.FromSections(name, kmlSections(t, legs), kmlStyling(t, legs, colorBy))
+}
+
+// kmlStyling returns the shared styles of the trail's KML files: routes coloured by colorBy (see
+// KMLConfig) with a balloon of their stats, and an icon for each category of waypoint.
+func kmlStyling(t *project.Trail, legs []legRoute, colorBy string) *kml.Styling {
+	byRoute := map[string]legRoute{}
+	categories := map[string]string{}
+	for _, leg := range legs {
+		byRoute[leg.Route.Name] = leg
+		for _, w := range leg.Waypoints {
+			categories[w.Name] = waypointCategory(leg, w)
+		}
+	}
+
+	styling := &kml.Styling{
+		Balloon: t.KML.Balloon,
+		Route: func(r gpx.Route) string {
+			leg, ok := byRoute[r.Name]
+			if !ok {
+				return "green"
+			}
+			switch colorBy {
+			case "leg":
+				return kml.Colors[(leg.Leg-1)%len(kml.Colors)].Name
+			case "section":
+				for i, s := range t.Sections {
+					if s.Includes(leg.Leg) {
+						return kml.Colors[i%len(kml.Colors)].Name
+					}
+				}
+			case "difficulty":
+				return difficulty(leg.Notes)
+			case "lodge":
+				if c, ok := lodgeColors[leg.Notes.Lodge]; ok {
+					return c
+				}
+			}
+			return "green"
+		},
+		Waypoint: func(w gpx.Waypoint) string {
+			if c, ok := categories[w.Name]; ok {
+				return "icon-" + c
+			}
+			return "icon-waypoint"
+		},
+		Data: func(r gpx.Route) []kml.Data {
+			leg, ok := byRoute[r.Name]
+			if !ok {
+				return nil
+			}
+			l := leg.Notes
+			time := ""
+			if l.Time > 0 {
+				time = fmt.Sprintf("%.1f hours", l.Time)
+			}
+			return []kml.Data{
+				{Name: "length", Value: fmt.Sprintf("%.1f", l.Length)},
+				{Name: "climb", Value: fmt.Sprintf("%.0f", l.Climb)},
+				{Name: "descent", Value: fmt.Sprintf("%.0f", l.Descent)},
+				{Name: "time", Value: time},
+				{Name: "lodge", Value: notes.LodgeString(l.Lodge)},
+			}
+		},
+	}
+	if styling.Balloon == "" {
+		styling.Balloon = defaultBalloon
+	}
+	for _, c := range []string{"start", "pass", "campsite", "guesthouse", "waypoint"} {
+		href := t.KML.Icons[c]
+		if href == "" {
+			href = defaultIcons[c]
+		}
+		styling.Styles = append(styling.Styles, &kml.Style{
+			Id:        "icon-" + c,
+			IconStyle: &kml.IconStyle{Icon: kml.Icon{Href: href}},
+		})
+	}
+	return styling
+}
+
+// waypointCategory returns whether a waypoint of a leg is the start of the leg (the waypoint with
+// the leg's description in some export profiles), a pass, a campsite or guesthouse at the end of the
+// leg, or any other waypoint.
+func waypointCategory(leg legRoute, w gpx.Waypoint) string {
+	if w.Name == leg.Route.Name {
+		return "start"
+	}
+	name := strings.TrimPrefix(w.Name, fmt.Sprintf("L%03d ", leg.Leg))
+	for _, p := range leg.Notes.Passes {
+		if p.Pass == name {
+			return "pass"
+		}
+	}
+	if name == leg.Notes.To {
+		switch leg.Notes.Lodge {
+		case notes.Campsite, notes.Shelter:
+			return "campsite"
+		case notes.Guesthouse, notes.Homestay:
+			return "guesthouse"
+		}
+	}
+	return "waypoint"
+}
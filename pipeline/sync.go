@@ -0,0 +1,43 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/project"
+	"github.com/dave/ght/sheets"
+)
+
+// Sync reads the trail notes from the google sheet and writes them to the trail notes JSON file.
+func Sync(t *project.Trail, opts Options) error {
+	ctx := context.Background()
+	c, err := sheets.New(ctx, t)
+	if err != nil {
+		return err
+	}
+	b, err := c.Fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	// make sure the sheet can be decoded before overwriting the trail notes
+	var sheet notes.Sheet
+	if err := json.Unmarshal(b, &sheet); err != nil {
+		return fmt.Errorf("error decoding sheet: %w", err)
+	}
+	fmt.Printf("%d legs, %d waypoints, %d passes\n", len(sheet.Legs), len(sheet.Waypoints), len(sheet.Passes))
+	if problems := sheet.Check(); len(problems) > 0 {
+		problems.Print(os.Stdout)
+	}
+
+	if fpath := t.Notes; !opts.skip(fpath) {
+		if err := ioutil.WriteFile(fpath, b, 0777); err != nil {
+			return fmt.Errorf("error writing file %q: %w", fpath, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package pipeline
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/dave/ght/geo"
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/kml"
+	"github.com/dave/ght/project"
+)
+
+// WriteTours writes tours-vN.kml, with a Google Earth tour of each leg that flies the camera along
+// the route and pauses at each waypoint and pass with its notes shown.
+func WriteTours(t *project.Trail, opts Options) error {
+	outDir := t.Output.Routes
+	if err := opts.mkdir("output.routes", outDir); err != nil {
+		return err
+	}
+	// legs that fail are left out and reported at the end
+	legs, failed := legRoutes(t, nil, opts)
+	if len(legs) == 0 && failed != nil {
+		return failed
+	}
+
+	// the waypoints are tour stops with ids instead, so the tours can open their balloons
+	routes := make([]legRoute, len(legs))
+	for i, leg := range legs {
+		routes[i] = leg
+		routes[i].Waypoints = nil
+	}
+	k := trailKML(t, t.Name, routes, t.KML.ColorBy)
+	k.XmlnsGx = kml.GxNamespace
+	stops := &kml.Folder{Name: "Tour stops", Visibility: 1}
+	for _, leg := range legs {
+		if len(leg.Route.Points) < 2 {
+			continue
+		}
+		k.Document.Tours = append(k.Document.Tours, legTour(t.Tour, leg, stops))
+	}
+	k.Document.Folders = append(k.Document.Folders, stops)
+
+	if fpath := filepath.Join(outDir, fmt.Sprintf("tours-v%v.kml", opts.Version)); !opts.skip(fpath) {
+		if err := kml.Save(k, fpath); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%d tours, %d stops\n", len(k.Document.Tours), len(stops.Placemarks))
+	return failed
+}
+
+// legTour returns the tour of a leg, adding a placemark to stops for each waypoint and pass it
+// pauses at.
+func legTour(c project.TourConfig, leg legRoute, stops *kml.Folder) *kml.Tour {
+	points := gpx.Locations(leg.Route.Points)
+	dist := make([]float64, len(points))
+	for i := 1; i < len(points); i++ {
+		dist[i] = dist[i-1] + geo.Distance(points[i-1].Lat, points[i-1].Lon, points[i].Lat, points[i].Lon)*1000
+	}
+
+	// the camera positions: a point every c.Step m along the route, and the end
+	var samples []int
+	for i := range points {
+		if len(samples) == 0 || dist[i]-dist[samples[len(samples)-1]] >= c.Step || i == len(points)-1 {
+			samples = append(samples, i)
+		}
+	}
+	lookAt := func(p gpx.Point, heading, rng float64) kml.LookAt {
+		return kml.LookAt{
+			Longitude:    p.Lon,
+			Latitude:     p.Lat,
+			Altitude:     c.Altitude,
+			Heading:      heading,
+			Tilt:         c.Tilt,
+			Range:        rng,
+			AltitudeMode: "relativeToGround",
+		}
+	}
+	heading := func(j int) float64 {
+		// look along the route towards the next camera position
+		from, to := points[samples[j]], points[samples[len(samples)-1]]
+		if j+1 < len(samples) {
+			to = points[samples[j+1]]
+		} else if j > 0 {
+			from = points[samples[j-1]]
+		}
+		return geo.Bearing(from.Lat, from.Lon, to.Lat, to.Lon)
+	}
+
+	type stop struct {
+		Index int // closest route point
+		ID    string
+		Point gpx.Point
+	}
+	var legStops []stop
+	// passes are usually waypoints too
+	waypoints := append([]gpx.Waypoint(nil), leg.Waypoints...)
+	for _, p := range leg.Passes {
+		found := false
+		for _, w := range leg.Waypoints {
+			found = found || w.Name == p.Name
+		}
+		if !found {
+			waypoints = append(waypoints, p)
+		}
+	}
+	for _, w := range waypoints {
+		id := fmt.Sprintf("L%03d-stop-%d", leg.Leg, len(legStops)+1)
+		legStops = append(legStops, stop{Index: gpx.Closest(points, w.Point), ID: id, Point: w.Point})
+		stops.Placemarks = append(stops.Placemarks, &kml.Placemark{
+			Id:          id,
+			Name:        w.Name,
+			Description: w.Desc,
+			Visibility:  1,
+			StyleUrl:    "#icon-" + waypointCategory(leg, w),
+			Point:       &kml.Point{Coordinates: kml.PointToCoordinates(w.Point)},
+		})
+	}
+	sort.SliceStable(legStops, func(i, j int) bool { return legStops[i].Index < legStops[j].Index })
+
+	tour := &kml.Tour{Name: leg.Route.Name}
+	add := func(step interface{}) {
+		tour.Playlist.Steps = append(tour.Playlist.Steps, step)
+	}
+	add(&kml.FlyTo{Duration: 5, Mode: "bounce", LookAt: lookAt(points[0], heading(0), c.Range)})
+	next := 0
+	for j, i := range samples {
+		if j > 0 {
+			add(&kml.FlyTo{Duration: (dist[i] - dist[samples[j-1]]) / c.Speed, Mode: "smooth", LookAt: lookAt(points[i], heading(j), c.Range)})
+		}
+		for ; next < len(legStops) && legStops[next].Index <= i; next++ {
+			s := legStops[next]
+			add(&kml.FlyTo{Duration: 2, Mode: "smooth", LookAt: lookAt(s.Point, heading(j), c.Range)})
+			add(kml.ShowBalloon(s.ID, true))
+			add(&kml.Wait{Duration: c.Pause})
+			add(kml.ShowBalloon(s.ID, false))
+		}
+	}
+	return tour
+}
@@ -0,0 +1,250 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/project"
+)
+
+// WaypointProblem is a mismatch between the waypoints and passes in the trail notes and the waypoints
+// in the GPX file of a leg.
+type WaypointProblem struct {
+	Leg     int
+	Problem string // e.g. "waypoint in sheet but not in GPX"
+	Name    string
+	Suggest string // closest name on the other side, if any are similar
+}
+
+func (p WaypointProblem) String() string {
+	if p.Suggest != "" {
+		return fmt.Sprintf("%s: %q (did you mean %q?)", p.Problem, p.Name, p.Suggest)
+	}
+	return fmt.Sprintf("%s: %q", p.Problem, p.Name)
+}
+
+// WaypointReport lists every waypoint problem found in a run, so they can all be fixed at once.
+type WaypointReport []WaypointProblem
+
+func (r WaypointReport) Error() string {
+	legs := map[int]bool{}
+	for _, p := range r {
+		legs[p.Leg] = true
+	}
+	return fmt.Sprintf("%d waypoint problems in %d legs", len(r), len(legs))
+}
+
+// Print writes the report grouped by leg.
+func (r WaypointReport) Print(w io.Writer) {
+	sorted := append(WaypointReport(nil), r...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Leg < sorted[j].Leg })
+	for i, p := range sorted {
+		if i == 0 || sorted[i-1].Leg != p.Leg {
+			fmt.Fprintf(w, "Leg %d:\n", p.Leg)
+		}
+		fmt.Fprintf(w, "  %s\n", p)
+	}
+	if len(r) > 0 {
+		fmt.Fprintln(w, r.Error())
+	}
+}
+
+// checkWaypoints checks the waypoints and passes in the trail notes match the waypoints in the GPX
+// file, and copies the location of each waypoint into the notes.
+func checkWaypoints(leg *notes.Leg, g gpx.GPX) WaypointReport {
+	var report WaypointReport
+	name := func(s string) string {
+		return fmt.Sprintf("L%03d %s", leg.Leg, s)
+	}
+	var gpxNames, sheetNames []string
+	for _, w := range g.Waypoints {
+		gpxNames = append(gpxNames, w.Name)
+	}
+	for _, w := range leg.Waypoints {
+		sheetNames = append(sheetNames, name(w.Name))
+	}
+	for _, p := range leg.Passes {
+		sheetNames = append(sheetNames, name(p.Pass))
+	}
+
+	for _, waypointFromNotes := range leg.Waypoints {
+		var found bool
+		for _, waypointFromGpx := range g.Waypoints {
+			if name(waypointFromNotes.Name) == waypointFromGpx.Name {
+				found = true
+				waypointFromNotes.Lat = waypointFromGpx.Lat
+				waypointFromNotes.Lon = waypointFromGpx.Lon
+				waypointFromNotes.Elevation = waypointFromGpx.Ele
+				break
+			}
+		}
+		if !found {
+			report = append(report, WaypointProblem{
+				Leg:     leg.Leg,
+				Problem: "waypoint in sheet but not in GPX",
+				Name:    name(waypointFromNotes.Name),
+				Suggest: suggest(name(waypointFromNotes.Name), gpxNames),
+			})
+		}
+	}
+
+	for _, waypointFromGpx := range g.Waypoints {
+		var found bool
+		for _, waypointFromNotes := range leg.Waypoints {
+			if name(waypointFromNotes.Name) == waypointFromGpx.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report = append(report, WaypointProblem{
+				Leg:     leg.Leg,
+				Problem: "waypoint in GPX but not in sheet",
+				Name:    waypointFromGpx.Name,
+				Suggest: suggest(waypointFromGpx.Name, sheetNames),
+			})
+		}
+	}
+
+	for _, pass := range leg.Passes {
+		var found bool
+		for _, waypointFromGpx := range g.Waypoints {
+			if name(pass.Pass) == waypointFromGpx.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report = append(report, WaypointProblem{
+				Leg:     leg.Leg,
+				Problem: "pass in sheet but not in GPX",
+				Name:    name(pass.Pass),
+				Suggest: suggest(name(pass.Pass), gpxNames),
+			})
+		}
+	}
+	return report
+}
+
+var legPrefix = regexp.MustCompile(`^L\d{3} `)
+
+// suggest returns the candidate closest to name by edit distance, or "" if none is close enough to
+// be a likely typo. The "L###" prefix is compared separately, so a waypoint with the wrong leg number
+// is still suggested.
+func suggest(name string, candidates []string) string {
+	strip := func(s string) string {
+		return strings.ToLower(legPrefix.ReplaceAllString(s, ""))
+	}
+	var best string
+	bestDistance := -1
+	for _, c := range candidates {
+		if c == name {
+			continue
+		}
+		d := levenshtein(strip(name), strip(c))
+		if bestDistance == -1 || d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	threshold := len(strip(name)) / 3
+	if threshold < 2 {
+		threshold = 2
+	}
+	if bestDistance == -1 || bestDistance > threshold {
+		return ""
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// Validate checks the trail notes against the schema, and the waypoints and passes of every leg
+// against the GPX files, and prints a full report, without writing anything.
+func Validate(t *project.Trail, opts Options) error {
+	sheet, err := notes.Decode(t.Notes)
+	if err != nil {
+		return err
+	}
+	problems := sheet.Check()
+	problems.Print(os.Stdout)
+	// invalid vlog days are in the problems
+	_ = sheet.Link(t.Start)
+
+	legsByLeg := map[int]*notes.Leg{}
+	for _, leg := range sheet.Legs {
+		legsByLeg[leg.Leg] = leg
+	}
+
+	routeFiles, err := ioutil.ReadDir(t.Gpx)
+	if err != nil {
+		return err
+	}
+
+	var report WaypointReport
+	var failed LegErrors
+	found := map[int]bool{}
+	for _, fileInfo := range routeFiles {
+		legNumber, ok := t.Leg(fileInfo.Name())
+		if !ok || !opts.Legs.Include(legNumber) {
+			continue
+		}
+		found[legNumber] = true
+		g, err := gpx.Load(filepath.Join(t.Gpx, fileInfo.Name()))
+		if err != nil {
+			failed.Add(legNumber, err)
+			continue
+		}
+		leg := legsByLeg[legNumber]
+		if leg == nil {
+			failed.Add(legNumber, fmt.Errorf("leg not found in trail notes"))
+			continue
+		}
+		report = append(report, checkWaypoints(leg, g)...)
+	}
+	for _, leg := range sheet.Legs {
+		if opts.Legs.Include(leg.Leg) && !found[leg.Leg] {
+			failed.Add(leg.Leg, fmt.Errorf("no GPX file"))
+		}
+	}
+
+	report.Print(os.Stdout)
+	if len(failed) > 0 {
+		return failed
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	if len(report) > 0 {
+		return report
+	}
+	fmt.Println("trail notes are valid and all waypoints and passes match")
+	return nil
+}
@@ -0,0 +1,301 @@
+// Package project is the project config file (ght.yaml) describing each trail and where its files live.
+package project
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Project is the project configuration file (ght.yaml), describing one or more trails. Relative paths
+// are resolved against the directory containing the config file, so the same file works on anyone's
+// machine.
+type Project struct {
+	Content string   `yaml:"content"` // site content directory, trail pages go in <content>/<section>/<slug>
+	Trails  []*Trail `yaml:"trails"`
+}
+
+// Trail describes one long-distance trek and where its files live.
+type Trail struct {
+	Name     string `yaml:"name"`      // e.g. "Great Himalaya Trail"
+	Slug     string `yaml:"slug"`      // e.g. "great-himalaya-trail", used in site URLs
+	Section  string `yaml:"section"`   // site section, e.g. "expeditions"
+	Start    string `yaml:"start"`     // where the first leg starts
+	LegFiles string `yaml:"leg_files"` // regexp matching GPX file names, capturing the leg number
+	Sheet    string `yaml:"sheet"`     // URL of the google sheet the trail notes are exported from
+
+	Sync SyncConfig `yaml:"sync"` // how the sync command reads the google sheet
+
+	Notes     string       `yaml:"notes"`      // trail notes JSON exported from the google sheet
+	Gpx       string       `yaml:"gpx"`        // directory of corrected GPX files (with waypoints), one per leg
+	DEM       string       `yaml:"dem"`        // directory of SRTM .hgt or GeoTIFF elevation tiles
+	Output    OutputConfig `yaml:"output"`     // directories generated files are written to
+	ImageURL  string       `yaml:"image_url"`  // base URL of the directories the map and elevation images are uploaded to
+	RoutesURL string       `yaml:"routes_url"` // base URL the routes and downloads files are served from
+
+	Releases []*Release `yaml:"releases"` // versions of the routes, listed on the GPS routes page
+
+	Sections []*Section `yaml:"sections"` // named runs of consecutive legs, summarised in the trail notes
+
+	Page     PageConfig `yaml:"page"`
+	Maps     MapConfig  `yaml:"maps"`
+	KML      KMLConfig  `yaml:"kml"`
+	Tour     TourConfig `yaml:"tour"`
+	Profiles []string   `yaml:"profiles"` // apps to write routes files for, e.g. maps-me or osmand (default maps-me)
+	Climb    string     `yaml:"climb"`    // climb algorithm of the stats command, see "ght stats -h"
+	Walking  string     `yaml:"walking"`  // walking time model of the stats command, see "ght stats -h"
+
+	// Content is the directory the trail pages are written to, from the project content directory,
+	// section and slug.
+	Content string `yaml:"-"`
+
+	legFiles *regexp.Regexp
+}
+
+type OutputConfig struct {
+	Routes     string `yaml:"routes"`     // combined GPX and KML route files
+	Maps       string `yaml:"maps"`       // map images
+	Elevations string `yaml:"elevations"` // elevation graphs
+	Corrected  string `yaml:"corrected"`  // GPX files with elevations from the DEM tiles
+	Downloads  string `yaml:"downloads"`  // GPX and KML files of each leg and section, and their manifest
+}
+
+// Section is a named run of consecutive legs, e.g. a region of the trail. Each section has a heading
+// and overview map in the trail notes, a folder in the KML files and GPX and KML downloads of its own.
+type Section struct {
+	Name        string `yaml:"name"`
+	From        int    `yaml:"from"`        // first leg
+	To          int    `yaml:"to"`          // last leg
+	Description string `yaml:"description"` // markdown shown under the heading in the trail notes
+}
+
+// Includes reports whether a leg is in the section.
+func (s *Section) Includes(leg int) bool {
+	return leg >= s.From && leg <= s.To
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slug returns the name of the section for use in file names and URLs, e.g. "everest-region".
+func (s *Section) Slug() string {
+	return strings.Trim(nonAlphanumeric.ReplaceAllString(strings.ToLower(s.Name), "-"), "-")
+}
+
+// SyncConfig holds the credentials used to read the google sheet through the Sheets API. Either
+// credentials or api_key must be set, unless endpoint points at a server that needs neither.
+type SyncConfig struct {
+	Credentials string `yaml:"credentials"` // service account key file, the sheet must be shared with it
+	APIKey      string `yaml:"api_key"`     // API key, for a sheet shared with anyone who has the link
+	Endpoint    string `yaml:"endpoint"`    // Sheets API base URL, e.g. to test against a local server
+}
+
+// Release is a version of the routes and trail notes.
+type Release struct {
+	Version int      `yaml:"version"`
+	Date    string   `yaml:"date"`    // e.g. "2020-02-28"
+	Changes []string `yaml:"changes"` // markdown, one item of the changelog each
+}
+
+// KMLConfig holds the styling of the routes and waypoints in KML files.
+type KMLConfig struct {
+	ColorBy string            `yaml:"color_by"` // leg, section, difficulty or lodge (default every route is green)
+	Icons   map[string]string `yaml:"icons"`    // icon URLs by waypoint category: start, pass, campsite, guesthouse or waypoint
+	Balloon string            `yaml:"balloon"`  // balloon text of the routes, with $[name], $[description], $[length], $[climb], $[descent], $[time] and $[lodge]
+}
+
+// TourConfig holds the camera of the Google Earth tour of each leg.
+type TourConfig struct {
+	Range    float64 `yaml:"range"`    // distance in m from the camera to the point it looks at (default 1500)
+	Tilt     float64 `yaml:"tilt"`     // degrees from looking straight down (default 60)
+	Altitude float64 `yaml:"altitude"` // height in m above the ground of the point the camera looks at (default 0)
+	Step     float64 `yaml:"step"`     // distance in m along the route between camera positions (default 250)
+	Speed    float64 `yaml:"speed"`    // speed of the camera along the route in m/s (default 150)
+	Pause    float64 `yaml:"pause"`    // seconds to pause at each waypoint and pass (default 6)
+}
+
+// UnmarshalYAML sets the defaults of the settings of a trail that can be 0, such as a tour tilt of 0
+// to look straight down, so they're only used when the setting is missing.
+func (t *Trail) UnmarshalYAML(value *yaml.Node) error {
+	type plain Trail
+	t.Tour = TourConfig{Range: 1500, Tilt: 60, Step: 250, Speed: 150, Pause: 6}
+	return value.Decode((*plain)(t))
+}
+
+// PageConfig holds the front matter and print layout of the trail notes page.
+type PageConfig struct {
+	Date        string              `yaml:"date"`          // e.g. "2020-02-28 00:00:00 +0000 UTC"
+	Author      string              `yaml:"author"`        // author of the trail notes and GPS routes pages
+	Image       string              `yaml:"image"`         // header image of the page with maps
+	ImageNoMaps string              `yaml:"image_no_maps"` // header image of the page with no maps
+	BlankPages  []int               `yaml:"blank_pages"`   // legs followed by a blank page when printed
+	Legs        map[int]PageLegInfo `yaml:"legs"`
+}
+
+type PageLegInfo struct {
+	ElevationWidth string `yaml:"elevation_width"` // max width of the elevation graph when printed, e.g. 80%
+}
+
+// MapConfig holds the zoom level of the map images and per-leg adjustments.
+type MapConfig struct {
+	Zoom int                `yaml:"zoom"`
+	Legs map[int]MapLegInfo `yaml:"legs"`
+}
+
+type MapLegInfo struct {
+	Zoom      int     `yaml:"zoom"`
+	LonOffset float64 `yaml:"lon_offset"` // degrees to move the centre of the map east (negative for west)
+}
+
+const defaultLegFiles = `^L(\d{3}).*\.gpx$`
+
+// Load reads a project config file.
+func Load(filename string) (*Project, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config %q: %w", filename, err)
+	}
+	p := &Project{}
+	if err := yaml.Unmarshal(b, p); err != nil {
+		return nil, fmt.Errorf("error decoding config %q: %w", filename, err)
+	}
+	dir := filepath.Dir(filename)
+	resolve := func(path *string) {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+	resolve(&p.Content)
+	if len(p.Trails) == 0 {
+		return nil, fmt.Errorf("config %q: no trails", filename)
+	}
+	for _, t := range p.Trails {
+		for _, path := range []*string{&t.Notes, &t.Gpx, &t.Output.Routes, &t.Output.Maps, &t.Output.Elevations, &t.Output.Corrected, &t.Output.Downloads, &t.DEM, &t.Sync.Credentials} {
+			resolve(path)
+		}
+		for name, value := range map[string]string{"name": t.Name, "slug": t.Slug, "notes": t.Notes, "gpx": t.Gpx} {
+			if value == "" {
+				return nil, fmt.Errorf("config %q: trail %q: %s must be set", filename, t.Slug, name)
+			}
+		}
+		if p.Content != "" {
+			t.Content = filepath.Join(p.Content, t.Section, t.Slug)
+		}
+		if t.LegFiles == "" {
+			t.LegFiles = defaultLegFiles
+		}
+		t.legFiles, err = regexp.Compile(t.LegFiles)
+		if err != nil {
+			return nil, fmt.Errorf("config %q: trail %q: invalid leg_files: %w", filename, t.Slug, err)
+		}
+		if t.legFiles.NumSubexp() != 1 {
+			return nil, fmt.Errorf("config %q: trail %q: leg_files must capture the leg number", filename, t.Slug)
+		}
+		for i, s := range t.Sections {
+			if s.Name == "" || s.From < 1 || s.To < s.From {
+				return nil, fmt.Errorf("config %q: trail %q: section %d must have a name and from <= to", filename, t.Slug, i+1)
+			}
+			if i > 0 && s.From <= t.Sections[i-1].To {
+				return nil, fmt.Errorf("config %q: trail %q: section %q overlaps %q", filename, t.Slug, s.Name, t.Sections[i-1].Name)
+			}
+		}
+		switch t.KML.ColorBy {
+		case "", "leg", "section", "difficulty", "lodge":
+		default:
+			return nil, fmt.Errorf("config %q: trail %q: kml color_by must be leg, section, difficulty or lodge", filename, t.Slug)
+		}
+		for i, r := range t.Releases {
+			if r.Version == 0 || r.Date == "" {
+				return nil, fmt.Errorf("config %q: trail %q: release %d must have a version and date", filename, t.Slug, i+1)
+			}
+		}
+		if c := t.Tour; c.Range <= 0 || c.Step <= 0 || c.Speed <= 0 || c.Pause < 0 || c.Altitude < 0 || c.Tilt < 0 || c.Tilt > 90 {
+			return nil, fmt.Errorf("config %q: trail %q: tour range, step and speed must be positive, pause and altitude can't be negative and tilt must be 0-90", filename, t.Slug)
+		}
+		if t.Maps.Zoom == 0 {
+			t.Maps.Zoom = 13
+		}
+	}
+	return p, nil
+}
+
+// Trail returns the trail with the given slug.
+func (p *Project) Trail(slug string) (*Trail, error) {
+	for _, t := range p.Trails {
+		if t.Slug == slug {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("no trail %q in config", slug)
+}
+
+// Leg returns the leg number of a GPX file, or false if the file name doesn't match the trail's
+// leg_files pattern.
+func (t *Trail) Leg(filename string) (int, bool) {
+	matches := t.legFiles.FindStringSubmatch(filename)
+	if matches == nil {
+		return 0, false
+	}
+	leg, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, false
+	}
+	return leg, true
+}
+
+var spreadsheetID = regexp.MustCompile(`/spreadsheets/d/([a-zA-Z0-9_-]+)`)
+
+// SpreadsheetID returns the ID of the google sheet, from its URL.
+func (t *Trail) SpreadsheetID() (string, error) {
+	if t.Sheet == "" {
+		return "", fmt.Errorf("sheet is not set in the config file")
+	}
+	matches := spreadsheetID.FindStringSubmatch(t.Sheet)
+	if matches == nil {
+		return "", fmt.Errorf("no spreadsheet ID in sheet URL %q", t.Sheet)
+	}
+	return matches[1], nil
+}
+
+// SectionOf returns the section a leg is in, or nil.
+func (t *Trail) SectionOf(leg int) *Section {
+	for _, s := range t.Sections {
+		if s.Includes(leg) {
+			return s
+		}
+	}
+	return nil
+}
+
+// Release returns the release of a version, or nil.
+func (t *Trail) Release(version int) *Release {
+	for _, r := range t.Releases {
+		if r.Version == version {
+			return r
+		}
+	}
+	return nil
+}
+
+// LatestVersion returns the highest version in the trail's releases, or 0 if it has none.
+func (t *Trail) LatestVersion() int {
+	var version int
+	for _, r := range t.Releases {
+		if r.Version > version {
+			version = r.Version
+		}
+	}
+	return version
+}
+
+// URL returns the site URL of a page of the trail, e.g. t.URL("gps-routes").
+func (t *Trail) URL(page string) string {
+	if t.Section == "" {
+		return fmt.Sprintf("/%s/%s/", t.Slug, page)
+	}
+	return fmt.Sprintf("/%s/%s/%s/", t.Section, t.Slug, page)
+}
@@ -0,0 +1,85 @@
+package project
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func load(t *testing.T, config string) (*Project, error) {
+	t.Helper()
+	fpath := filepath.Join(t.TempDir(), "ght.yaml")
+	if err := ioutil.WriteFile(fpath, []byte(config), 0666); err != nil {
+		t.Fatal(err)
+	}
+	return Load(fpath)
+}
+
+const trail = `trails:
+  - name: Great Himalaya Trail
+    slug: great-himalaya-trail
+    notes: trailnotes.json
+    gpx: data/gpx
+`
+
+func TestTourConfig(t *testing.T) {
+	for _, test := range []struct {
+		tour string
+		want TourConfig
+	}{
+		{"", TourConfig{Range: 1500, Tilt: 60, Step: 250, Speed: 150, Pause: 6}},
+		{"    tour: {tilt: 0, pause: 0}\n", TourConfig{Range: 1500, Tilt: 0, Step: 250, Speed: 150, Pause: 0}},
+		{"    tour: {range: 800, altitude: 50}\n", TourConfig{Range: 800, Tilt: 60, Altitude: 50, Step: 250, Speed: 150, Pause: 6}},
+	} {
+		p, err := load(t, trail+test.tour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Trails[0].Tour; got != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.tour, got, test.want)
+		}
+	}
+
+	for _, tour := range []string{"{range: 0}", "{speed: 0}", "{tilt: 91}", "{tilt: -1}", "{pause: -1}"} {
+		if _, err := load(t, trail+"    tour: "+tour+"\n"); err == nil {
+			t.Errorf("%s: expected an error", tour)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	p, err := load(t, trail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := p.Trails[0]
+	if !filepath.IsAbs(tr.Gpx) || filepath.Base(filepath.Dir(tr.Gpx)) != "data" {
+		t.Errorf("gpx not resolved against the config directory: %q", tr.Gpx)
+	}
+	if leg, ok := tr.Leg("L012 Dhunche.gpx"); !ok || leg != 12 {
+		t.Errorf("got leg %d %v, want 12", leg, ok)
+	}
+	if _, ok := tr.Leg("notes.gpx"); ok {
+		t.Error("notes.gpx isn't a leg")
+	}
+	if _, err := load(t, "trails:\n  - name: x\n    slug: x\n"); err == nil {
+		t.Error("expected an error for a trail without notes and gpx")
+	}
+}
+
+func TestLatestVersion(t *testing.T) {
+	p, err := load(t, trail+`    releases:
+      - {version: 11, date: 2020-02-28}
+      - {version: 12, date: 2021-03-01}
+      - {version: 10, date: 2019-11-10}
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Trails[0].LatestVersion(); got != 12 {
+		t.Errorf("got version %d, want 12", got)
+	}
+	if got := (&Trail{}).LatestVersion(); got != 0 {
+		t.Errorf("got version %d with no releases, want 0", got)
+	}
+}
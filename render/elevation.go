@@ -0,0 +1,112 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/dave/ght/geo"
+	"github.com/dave/ght/gpx"
+	"github.com/wcharczuk/go-chart"
+	"github.com/wcharczuk/go-chart/drawing"
+)
+
+// Elevation draws the elevation profile of a leg, with distance along the route in km on the x axis.
+func Elevation(pts []gpx.Point) chart.Chart {
+
+	series := chart.ContinuousSeries{}
+	var d, minEle, maxEle float64
+	for i, point := range pts {
+		if i > 0 {
+			last := pts[i-1]
+			d += geo.Distance(last.Lat, last.Lon, point.Lat, point.Lon) * 1000
+		}
+		series.XValues = append(series.XValues, d)
+		series.YValues = append(series.YValues, point.Ele)
+		if point.Ele > maxEle || maxEle == 0 {
+			maxEle = point.Ele
+		}
+		if point.Ele < minEle || minEle == 0 {
+			minEle = point.Ele
+		}
+	}
+
+	maxX := math.Ceil(d/1000) * 1000
+	minY := math.Floor(minEle/1000) * 1000
+	maxY := math.Ceil(maxEle/1000) * 1000
+
+	if maxY-maxEle < 100 {
+		maxY += 1000
+	}
+
+	if minEle-minY < 100 && minY > 0 {
+		minY -= 1000
+	}
+
+	plot := chart.Chart{}
+	plot.Series = []chart.Series{series}
+
+	plot.YAxis.Name = "Elevation"
+	plot.YAxis.Range = &chart.ContinuousRange{Min: minY, Max: maxY}
+	plot.YAxis.Style.Show = true
+	for i := minY; i <= maxY; i += 1000.0 {
+		plot.YAxis.GridLines = append(plot.YAxis.GridLines, chart.GridLine{Value: i})
+		plot.YAxis.Ticks = append(plot.YAxis.Ticks, chart.Tick{Value: i, Label: fmt.Sprintf("%dm", int(i))})
+		for j := i + 100; j <= i+900; j += 100 {
+			if j == i+500 {
+				plot.YAxis.GridLines = append(plot.YAxis.GridLines, chart.GridLine{Value: j, IsMinor: true})
+			} else {
+				plot.YAxis.GridLines = append(plot.YAxis.GridLines, chart.GridLine{Value: j, IsMinor: true, Style: chart.Style{
+					Show:            true,
+					StrokeWidth:     1,
+					StrokeColor:     drawing.Color{R: 0xDD, G: 0xDD, B: 0xDD, A: 0xFF},
+					StrokeDashArray: []float64{5.0, 5.0},
+				}})
+			}
+		}
+	}
+	plot.YAxis.GridMinorStyle = chart.Style{
+		Show:        true,
+		StrokeWidth: 1,
+		StrokeColor: drawing.Color{R: 0xDD, G: 0xDD, B: 0xDD, A: 0xFF},
+	}
+	plot.YAxis.GridMajorStyle = chart.Style{
+		Show:        true,
+		StrokeWidth: 1,
+		StrokeColor: drawing.Color{R: 0x66, G: 0x66, B: 0x66, A: 0xFF},
+	}
+
+	plot.XAxis.Name = "Distance"
+	plot.XAxis.Range = &chart.ContinuousRange{Min: 0, Max: maxX}
+	plot.XAxis.Style.Show = true
+	for i := 0.0; i <= maxX; i += 1000.0 {
+		plot.XAxis.GridLines = append(plot.XAxis.GridLines, chart.GridLine{Value: i})
+		plot.XAxis.Ticks = append(plot.XAxis.Ticks, chart.Tick{Value: i, Label: fmt.Sprintf("%dkm", int(i)/1000)})
+	}
+	plot.XAxis.GridMajorStyle = chart.Style{
+		Show:        true,
+		StrokeWidth: 1,
+		StrokeColor: chart.ColorAlternateLightGray,
+	}
+
+	plot.Height = int((1500/maxX)*(maxY-minY)) + 35
+	plot.Width = 1500
+
+	return plot
+}
+
+// WriteChart renders a chart to a PNG file.
+func WriteChart(c chart.Chart, fpath string) error {
+	f, err := os.Create(fpath)
+	if err != nil {
+		return fmt.Errorf("error creating file %q: %w", fpath, err)
+	}
+	if err := c.Render(chart.PNG, f); err != nil {
+		f.Close()
+		return fmt.Errorf("error rendering chart %q: %w", fpath, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("error writing file %q: %w", fpath, err)
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Package render draws the maps, elevation profiles and trail notes pages.
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"github.com/dave/ght/geo"
+	"github.com/dave/ght/gpx"
+	"github.com/dave/ght/project"
+	sm "github.com/flopp/go-staticmaps"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+)
+
+var ApiKey string
+
+// newContext returns a 1200x1200 map context using the Thunderforest landscape tiles.
+func newContext() *sm.Context {
+	ctx := sm.NewContext()
+
+	/*
+
+		Create a file apikey.go, with the contents:
+
+		package main
+
+		import "github.com/dave/ght/render"
+
+		func init() {
+			render.ApiKey = "YOUR_API_KEY"
+		}
+
+	*/
+
+	tp := new(sm.TileProvider)
+	tp.Name = "thunderforest-landscape"
+	tp.Attribution = "Maps (c) Thundeforest; Data (c) OSM and contributors, ODbL"
+	tp.TileSize = 256
+	tp.URLPattern = "https://tile.thunderforest.com/landscape/%[2]d/%[3]d/%[4]d.png?apikey=" + ApiKey
+
+	ctx.SetTileProvider(tp)
+
+	ctx.SetSize(1200, 1200)
+	return ctx
+}
+
+// SectionMap draws an overview map of a section of the trail, with the route of each leg and a marker
+// labelled with the leg number at the start of each leg. The zoom level is chosen to fit the whole
+// section.
+func SectionMap(legs []int, pts [][]gpx.Point) (image.Image, int, error) {
+	ctx := newContext()
+	var any bool
+	for i, points := range pts {
+		if len(points) == 0 {
+			continue
+		}
+		any = true
+		var path []s2.LatLng
+		for _, p := range points {
+			path = append(path, s2.LatLngFromDegrees(p.Lat, p.Lon))
+		}
+		// alternate colours so the ends of the legs can be seen
+		c := color.RGBA{0xcc, 0, 0, 0xcc}
+		if i%2 == 1 {
+			c = color.RGBA{0, 0, 0xcc, 0xcc}
+		}
+		ctx.AddPath(sm.NewPath(path, c, 3.0))
+		m := sm.NewMarker(
+			s2.LatLngFromDegrees(points[0].Lat, points[0].Lon),
+			color.RGBA{0, 0xcc, 0, 0xff},
+			15.0,
+		)
+		m.Label = fmt.Sprint(legs[i])
+		m.LabelColor = color.Black
+		ctx.AddMarker(m)
+	}
+	if !any {
+		return nil, 0, fmt.Errorf("no points")
+	}
+	return ctx.Render()
+}
+
+// Map draws a map of one leg using OpenStreetMap, with its waypoints and the routes of the
+// neighbouring legs in others. It returns the image and the zoom level it was rendered at.
+func Map(t *project.Trail, leg int, pts []gpx.Point, waypoints []gpx.Waypoint, others [][]gpx.Point) (image.Image, int, error) {
+	if len(pts) == 0 {
+		return nil, 0, fmt.Errorf("no points")
+	}
+
+	ctx := newContext()
+
+	var minLat, maxLat, minLon, maxLon float64
+	for i, point := range pts {
+		if i == 0 || point.Lat < minLat {
+			minLat = point.Lat
+		}
+		if i == 0 || point.Lat > maxLat {
+			maxLat = point.Lat
+		}
+		if i == 0 || point.Lon < minLon {
+			minLon = point.Lon
+		}
+		if i == 0 || point.Lon > maxLon {
+			maxLon = point.Lon
+		}
+	}
+
+	bb := s2.NewRectBounder()
+	bb.AddPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(minLat, minLon)))
+	bb.AddPoint(s2.PointFromLatLng(s2.LatLngFromDegrees(maxLat, maxLon)))
+
+	center := bb.RectBound().Center()
+	zoom := t.Maps.Zoom
+	if info, ok := t.Maps.Legs[leg]; ok {
+		// move the centre for some legs so all the waypoints are visible
+		center.Lng += s1.Angle(info.LonOffset) * s1.Degree
+		if info.Zoom != 0 {
+			zoom = info.Zoom
+		}
+	}
+	ctx.SetCenter(center)
+	ctx.SetZoom(zoom)
+
+	{
+
+		otherLegColor := color.RGBA{0, 0, 0x44, 0x44}
+		thisLegColor := color.RGBA{0xcc, 0, 0, 0xcc}
+		drawPath := func(points []gpx.Point, c color.RGBA) {
+			var d float64
+			for i, v := range points {
+				if i > 0 {
+					d += geo.Distance(v.Lat, v.Lon, points[i-1].Lat, points[i-1].Lon)
+					if d > 0.2 {
+						d = 0
+					} else {
+						continue
+					}
+				}
+				ctx.AddCircle(sm.NewCircle(
+					s2.LatLngFromDegrees(v.Lat, v.Lon),
+					color.RGBA{0xff, 0, 0, 0xff},
+					c,
+					50.0,
+					0.0,
+				))
+			}
+		}
+		for _, other := range others {
+			drawPath(other, otherLegColor)
+		}
+
+		drawPath(pts, thisLegColor)
+	}
+
+	ctx.AddMarker(
+		sm.NewMarker(
+			s2.LatLngFromDegrees(pts[0].Lat, pts[0].Lon),
+			color.RGBA{0, 0xcc, 0, 0xff},
+			15.0,
+		),
+	)
+	for _, p := range waypoints {
+		m := sm.NewMarker(
+			s2.LatLngFromDegrees(p.Lat, p.Lon),
+			color.RGBA{0xff, 0, 0, 0xff},
+			15.0,
+		)
+		m.Label = p.Name
+		m.LabelColor = color.Black
+		ctx.AddMarker(m)
+	}
+
+	return ctx.Render()
+}
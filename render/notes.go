@@ -1,32 +1,30 @@
-package main
+package render
 
 import (
-	"bytes"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"math"
-	"strconv"
-	"strings"
 	"text/template"
 
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/project"
 	"github.com/dustin/go-humanize"
 )
 
 var trailNotesTemplate = template.Must(template.New("main").Funcs(functions).Parse(`---
 type: report
-date: 2020-02-28 00:00:00 +0000 UTC
-publishDate: 2020-02-28 00:00:00 +0000 UTC
+date: {{ .Trail.Page.Date }}
+publishDate: {{ .Trail.Page.Date }}
 slug: trail-notes{{ if not .Maps }}-no-maps{{ end }}
 translationKey: trail-notes{{ if not .Maps }}-no-maps{{ end }}
 title: Trail notes{{ if not .Maps }} (no maps){{ end }}
-description: Comprehensive trail notes for the Great Himalaya Trail{{ if not .Maps }} (no maps){{ end }}.
-image: "/v1553075075/{{ if .Maps }}compass-390054_1920_hz27dl.jpg{{ else }}compass-1753659_1920_h82a3n.jpg{{ end }}"
+description: Comprehensive trail notes for the {{ .Trail.Name }}{{ if not .Maps }} (no maps){{ end }}.
+image: "{{ if .Maps }}{{ .Trail.Page.Image }}{{ else }}{{ .Trail.Page.ImageNoMaps }}{{ end }}"
 keywords: [trail-notes]
-author: dave
-featured: false
+{{ with .Trail.Page.Author }}author: {{ . }}
+{{ end }}featured: false
 social_posts: false
-social_date: 2020-02-28 00:00:00 +0000 UTC
+social_date: {{ .Trail.Page.Date }}
 hashtags: "#trail-notes"
 title_has_context: false
 ---
@@ -81,17 +79,35 @@ title_has_context: false
 	.no-print {
 		display: none;
 	}
-	img[src*="#elev028"] {
-	   max-width: 80%;
-	}
+{{- range $leg, $l := .Trail.Page.Legs }}{{ with $l.ElevationWidth }}
+	img[src*="#elev{{ printf "%.03d" $leg }}"] {
+		max-width: {{ . }};
+	}{{ end }}{{ end }}
 }
 </style>
 
 <div class="no-print">
 
-This is version {{ .Version }} of the trail notes. GPS routes for these trail notes are [available here](/expeditions/great-himalaya-trail/gps-routes/).
+This is version {{ .Version }} of the trail notes. GPS routes for these trail notes are [available here]({{ .Trail.URL "gps-routes" }}).
 
-There are versions of this page [with maps](/expeditions/great-himalaya-trail/trail-notes/) or [with no maps](/expeditions/great-himalaya-trail/trail-notes-no-maps/), and you can find the data used to generate this page [as a Google sheet](https://docs.google.com/spreadsheets/d/14x_OJ4mJNoHuj1LnYnyGULdE3P9kG6CwOdY1t0sv_H8/edit).
+There are versions of this page [with maps]({{ .Trail.URL "trail-notes" }}) or [with no maps]({{ .Trail.URL "trail-notes-no-maps" }}){{ if .Trail.Sheet }}, and you can find the data used to generate this page [as a Google sheet]({{ .Trail.Sheet }}){{ end }}.
+{{ with .Climb }}
+Climb and descent are calculated from the GPS routes by {{ . }}.
+{{ end }}{{ with .Walking }}
+Walking times are estimated using {{ . }}, and don't include breaks.
+{{ end }}
+</div>
+
+<div class="no-page-break">
+
+# Summary
+
+|   | Legs | Distance | Climb / descent | Highest point | Passes over 5,000 m | Nights camping / guesthouse |
+| - | - | - | - | - | - | - |
+{{ range .Sections }}{{ template "totals" . }}{{ end }}{{ with .Total }}{{ template "totals" . }}{{ end }}
+</div>
+
+<div class="no-print">
 
 # Trail notes
 
@@ -99,6 +115,24 @@ There are versions of this page [with maps](/expeditions/great-himalaya-trail/tr
 
 {{ range .Legs }}
 
+{{ with index $.Headings .Leg }}
+
+<div class="no-page-break" id="{{ .Slug }}">
+
+# {{ .Name }}
+
+{{ with .Description }}{{ . }}{{ end }}
+
+{{ if $.Maps }}
+
+![]({{ $.MapsURL }}/S-{{ .Slug }}.jpg)
+
+{{ end }}
+
+</div>
+
+{{ end }}
+
 <div class="no-page-break" id="L{{ printf "%.03d" .Leg }}">
 
 ## Leg {{ .Leg }}: {{ .From }} to {{ .To }}
@@ -149,7 +183,8 @@ Accommodation: {{ .LodgeString }} - {{ .QualityString }}
 | - | - |- |
 | Length | {{ printf "%.1f" .Length }} km | {{ printf "%.1f" (miles .Length) }} miles |
 | Climb / descent | {{ comma (round .Climb) }} / {{ comma (round .Descent) }} m | {{ comma (round (feet .Climb)) }} / {{ comma (round (feet .Descent)) }} ft |
-<!--| Start / end |  {{ comma (round .Start) }} / {{ comma (round .End) }} m |  {{ comma (round (feet .Start)) }} / {{ comma (round (feet .End)) }} ft |
+{{ if .Time }}| Walking time | {{ hours .Time }} |   |
+{{ end }}<!--| Start / end |  {{ comma (round .Start) }} / {{ comma (round .End) }} m |  {{ comma (round (feet .Start)) }} / {{ comma (round (feet .End)) }} ft |
 | Top / bottom |  {{ comma (round .Top) }} / {{ comma (round .Bottom) }} m  |  {{ comma (round (feet .Top)) }} / {{ comma (round (feet .Bottom)) }} ft |-->
 
 </div>
@@ -158,7 +193,7 @@ Accommodation: {{ .LodgeString }} - {{ .QualityString }}
 
 #### Elevation <span class="print-only">(leg {{ .Leg }})</span>
 
-![](https://storage.googleapis.com/wilderness-prime-static/elev3/E{{ printf "%.03d" .Leg }}.png#elev{{ printf "%.03d" .Leg }})
+![]({{ $.ElevationsURL }}/E{{ printf "%.03d" .Leg }}.png#elev{{ printf "%.03d" .Leg }})
 
 </div>
 
@@ -168,13 +203,13 @@ Accommodation: {{ .LodgeString }} - {{ .QualityString }}
 
 #### Map <span class="print-only">(leg {{ .Leg }})</span>
 
-![](https://storage.googleapis.com/wilderness-prime-static/maps3/L{{ printf "%.03d" .Leg }}.jpg)
+![]({{ $.MapsURL }}/L{{ printf "%.03d" .Leg }}.jpg)
 
 </div>
 
 <div class="page-break"></div>
 
-{{ if or (or (eq .Leg 87) (eq .Leg 62)) (eq .Leg 22) }}
+{{ if blank $.Trail.Page.BlankPages .Leg }}
 
 <div class="print-only">
 
@@ -189,9 +224,26 @@ This page is intentionally left blank.
 {{ end }}
 
 {{ end }}
-`))
+{{ define "totals" }}| [{{ .Name }}](#L{{ printf "%.03d" .FirstLeg }}) | {{ .FirstLeg }}-{{ .LastLeg }} | {{ comma .Length }} km / {{ comma (miles .Length) }} miles | {{ comma (round .Climb) }} / {{ comma (round .Descent) }} m | {{ comma (round .Highest) }} m (leg {{ .HighestLeg }}) | {{ .HighPasses }} | {{ .Camping }} / {{ .Guesthouse }} |
+{{ end }}`))
 
 var functions = template.FuncMap{
+	"blank": func(legs []int, leg int) bool {
+		for _, l := range legs {
+			if l == leg {
+				return true
+			}
+		}
+		return false
+	},
+	"hours": func(h float64) string {
+		// to the nearest quarter of an hour
+		m := int(math.Round(h*4)) * 15
+		if m < 60 {
+			return fmt.Sprintf("%d min", m)
+		}
+		return fmt.Sprintf("%dh %02dm", m/60, m%60)
+	},
 	"comma": func(i interface{}) string {
 		switch j := i.(type) {
 		case float64:
@@ -248,116 +300,27 @@ var functions = template.FuncMap{
 	},
 }
 
-func CreateTrailNotes(version int) error {
-	b, err := ioutil.ReadFile(`/Users/dave/src/ght/trailnotes.json`)
-	if err != nil {
-		return err
-	}
-	var notes TrailNotesSheetStruct
-	if err := json.Unmarshal(b, &notes); err != nil {
-		return err
-	}
-	legs := notes.Legs
-	for i, leg := range legs {
-		if i == 0 {
-			leg.From = "Taplejung"
-		} else {
-			leg.From = legs[i-1].To
-		}
-		for _, waypoint := range notes.Waypoints {
-			if waypoint.Leg == leg.Leg {
-				leg.Waypoints = append(leg.Waypoints, waypoint)
-			}
-		}
-		for _, pass := range notes.Passes {
-			if pass.Leg == leg.Leg {
-				leg.Passes = append(leg.Passes, pass)
-			}
-		}
-		if leg.Vlog != nil {
-			days := strings.Split(fmt.Sprint(leg.Vlog), ",")
-			for _, day := range days {
-				d, err := strconv.Atoi(day)
-				if err != nil {
-					return err
-				}
-				leg.Days = append(leg.Days, d)
-			}
-		}
+// TrailNotesData is the data the trail notes template is executed with.
+type TrailNotesData struct {
+	Maps    bool // include the map of each leg
+	Legs    []*notes.Leg
+	Version int
 
-		qualityString := func(i int, t string) string {
-			switch i {
-			case 1:
-				switch t {
-				case "T", "R":
-					// trail or route
-					return "1/5 (major problems)"
-				case "C", "S":
-					// campsite, shelter
-					return "1/5 (awful)"
-				case "G", "H":
-					// guesthouse or homestay
-					return "1/5 (basic)"
-				default:
-					return "1/5"
-				}
-			case 2:
-				return "2/5 (below average)"
-			case 3:
-				return "3/5 (average)"
-			case 4:
-				return "4/5 (above average)"
-			case 5:
-				return "5/5 (excellent)"
-			default:
-				return "(unknown)"
-			}
-		}
-
-		leg.TrailString = qualityString(leg.Trail, "T")
-		leg.RouteString = qualityString(leg.Route, "R")
-		leg.QualityString = qualityString(leg.Quality, leg.Lodge)
-
-		switch leg.Lodge {
-		case "C":
-			leg.LodgeString = "campsite"
-		case "S":
-			leg.LodgeString = "shelter"
-		case "H":
-			leg.LodgeString = "homestay"
-		case "G":
-			leg.LodgeString = "guesthouse"
-		default:
-			leg.LodgeString = "unknown"
-		}
-	}
-	var out bytes.Buffer
-
-	data := struct {
-		Maps    bool
-		Legs    []*LegStruct
-		Version int
-	}{
-		Maps:    true,
-		Legs:    legs,
-		Version: version,
-	}
+	// MapsURL and ElevationsURL are where the map and elevation images are served from, without a
+	// trailing slash.
+	MapsURL, ElevationsURL string
 
-	if err := trailNotesTemplate.Execute(&out, data); err != nil {
-		return err
-	}
-	if err := ioutil.WriteFile("/Users/dave/src/wildernessprime/content/expeditions/great-himalaya-trail/trail-notes.en.md", out.Bytes(), 0777); err != nil {
-		return err
-	}
+	Trail    *project.Trail
+	Climb    string         // description of the climb algorithm the stats were calculated with
+	Walking  string         // description of the walking time model
+	Total    notes.Totals   // the whole trail
+	Sections []notes.Totals // each section of the trail
 
-	out = bytes.Buffer{}
-	data.Maps = false
-	if err := trailNotesTemplate.Execute(&out, data); err != nil {
-		return err
-	}
-	if err := ioutil.WriteFile("/Users/dave/src/wildernessprime/content/expeditions/great-himalaya-trail/trail-notes-no-maps.en.md", out.Bytes(), 0777); err != nil {
-		return err
-	}
+	// Headings are the sections of the trail, by the first leg of each on the page.
+	Headings map[int]*project.Section
+}
 
-	return nil
+// TrailNotes writes the trail notes page in markdown.
+func TrailNotes(w io.Writer, data TrailNotesData) error {
+	return trailNotesTemplate.Execute(w, data)
 }
@@ -0,0 +1,53 @@
+package render
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/dave/ght/notes"
+	"github.com/dave/ght/project"
+)
+
+func TestTrailNotes(t *testing.T) {
+	data := TrailNotesData{
+		Maps:          true,
+		Legs:          []*notes.Leg{{Leg: 28, From: "Dobato", To: "Pass Camp"}},
+		Version:       11,
+		MapsURL:       "https://example.com/maps",
+		ElevationsURL: "https://example.com/elevations",
+		Trail: &project.Trail{
+			Name: "Test Trail",
+			Page: project.PageConfig{
+				Author: "someone",
+				Legs:   map[int]project.PageLegInfo{28: {ElevationWidth: "80%"}},
+			},
+		},
+	}
+	var out bytes.Buffer
+	if err := TrailNotes(&out, data); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"\nauthor: someone\n",
+		"img[src*=\"#elev028\"] {\n\t\tmax-width: 80%;\n\t}",
+		"![](https://example.com/elevations/E028.png#elev028)",
+		"![](https://example.com/maps/L028.jpg)",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("no %q in:\n%s", want, out.String())
+		}
+	}
+
+	// with no author or tweaks, there are none
+	data.Trail.Page = project.PageConfig{}
+	out.Reset()
+	if err := TrailNotes(&out, data); err != nil {
+		t.Fatal(err)
+	}
+	for _, unwanted := range []string{"author:", "max-width"} {
+		if strings.Contains(out.String(), unwanted) {
+			t.Errorf("found %q in:\n%s", unwanted, out.String())
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"io"
+	"text/template"
+
+	"github.com/dave/ght/project"
+	"github.com/dustin/go-humanize"
+)
+
+var gpsRoutesTemplate = template.Must(template.New("main").Funcs(template.FuncMap{"bytes": bytesString}).Parse(`---
+type: report
+date: {{ .Release.Date }}
+publishDate: {{ .Release.Date }}
+slug: gps-routes
+translationKey: gps-routes
+title: GPS routes
+description: GPS routes and waypoints for the {{ .Trail.Name }}, as GPX and KML files.
+keywords: [gps-routes]
+{{ with .Trail.Page.Author }}author: {{ . }}
+{{ end }}featured: false
+social_posts: false
+social_date: {{ .Release.Date }}
+hashtags: "#gps-routes"
+title_has_context: false
+---
+
+This is version {{ .Release.Version }} of the GPS routes, released on {{ .Release.Date }}. They go with version {{ .Release.Version }} of the [trail notes]({{ .Trail.URL "trail-notes" }}), and the route and waypoint descriptions are taken from them.
+
+# Whole trail
+
+Every leg of the trail in one file, as GPX, KML or GeoJSON (for web maps), and in the form each of these apps reads best (e.g. maps.me doesn't show the descriptions of routes, so its file has a waypoint at the start of each leg with the description).
+
+{{ template "files" .Whole }}
+{{ with .Sections }}
+# Sections
+
+| Section | Download |
+| - | - |
+{{ range . }}| {{ .Name }} | {{ template "links" .Files }} |
+{{ end }}{{ end }}{{ with .Legs }}
+# Legs
+
+| Leg | Download |
+| - | - |
+{{ range . }}| {{ .Name }} | {{ template "links" .Files }} |
+{{ end }}{{ end }}
+# Changelog
+{{ range .Releases }}
+## Version {{ .Version }} ({{ .Date }})
+{{ range .Changes }}
+* {{ . }}{{ end }}
+{{ end }}
+{{ define "files" }}{{ range . }}* [{{ .Label }}]({{ .URL }}) ({{ bytes .Size }})
+{{ end }}{{ end }}
+{{ define "links" }}{{ range $i, $f := . }}{{ if $i }} · {{ end }}[{{ .Label }}]({{ .URL }}) ({{ bytes .Size }}){{ end }}{{ end }}`))
+
+func bytesString(size int) string {
+	return humanize.Bytes(uint64(size))
+}
+
+// RouteFile is a routes file on the GPS routes page.
+type RouteFile struct {
+	Label string // e.g. "GPX"
+	URL   string
+	Size  int // bytes
+}
+
+// RouteDownload is a leg or section with its routes files.
+type RouteDownload struct {
+	Name  string
+	Files []RouteFile
+}
+
+// GPSRoutesData is the data the GPS routes template is executed with.
+type GPSRoutesData struct {
+	Trail    *project.Trail
+	Release  *project.Release   // the current version
+	Releases []*project.Release // every version, newest first
+	Whole    []RouteFile        // the files of the whole trail
+	Sections []RouteDownload
+	Legs     []RouteDownload
+}
+
+// GPSRoutes writes the GPS routes page in markdown.
+func GPSRoutes(w io.Writer, data GPSRoutesData) error {
+	return gpsRoutesTemplate.Execute(w, data)
+}
@@ -0,0 +1,225 @@
+// Package sheets reads the trail notes from the google sheet through the Sheets API.
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/dave/ght/project"
+	"google.golang.org/api/option"
+	"google.golang.org/api/sheets/v4"
+)
+
+// Client reads the google sheet of one trail.
+type Client struct {
+	id  string
+	srv *sheets.Service
+}
+
+// New creates a client for the google sheet of a trail, using the credentials in its sync config.
+func New(ctx context.Context, t *project.Trail) (*Client, error) {
+	id, err := t.SpreadsheetID()
+	if err != nil {
+		return nil, err
+	}
+	var opts []option.ClientOption
+	switch {
+	case t.Sync.Credentials != "":
+		opts = append(opts, option.WithCredentialsFile(t.Sync.Credentials))
+	case t.Sync.APIKey != "":
+		opts = append(opts, option.WithAPIKey(t.Sync.APIKey))
+	case t.Sync.Endpoint != "":
+		opts = append(opts, option.WithoutAuthentication())
+	default:
+		return nil, fmt.Errorf("sync.credentials or sync.api_key must be set in the config file")
+	}
+	if t.Sync.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(t.Sync.Endpoint))
+	}
+	srv, err := sheets.NewService(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating sheets client: %w", err)
+	}
+	return &Client{id: id, srv: srv}, nil
+}
+
+// Fetch reads the Legs, Waypoints and Passes tabs and returns them as trail notes JSON, in the same
+// form as json-sheets-export/export_to_json.js: the first row of each tab is the column names, empty
+// cells are left out and empty rows are skipped.
+func (c *Client) Fetch(ctx context.Context) ([]byte, error) {
+	resp, err := c.srv.Spreadsheets.Values.BatchGet(c.id).
+		Ranges("Legs", "Waypoints", "Passes").
+		ValueRenderOption("UNFORMATTED_VALUE").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return nil, fmt.Errorf("error reading sheet: %w", err)
+	}
+	if len(resp.ValueRanges) != 3 {
+		return nil, fmt.Errorf("error reading sheet: got %d ranges, expected 3", len(resp.ValueRanges))
+	}
+	out := struct {
+		Legs, Waypoints, Passes []row
+	}{
+		Legs:      rows(resp.ValueRanges[0].Values),
+		Waypoints: rows(resp.ValueRanges[1].Values),
+		Passes:    rows(resp.ValueRanges[2].Values),
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// UpdateLegs writes values to the Legs tab. legs maps each leg number to the new values of its row,
+// keyed by column name. Values of the optional columns are left out if the sheet doesn't have them,
+// e.g. columns added to the trail notes after the sheet was made.
+func (c *Client) UpdateLegs(ctx context.Context, legs map[int]map[string]interface{}, optional ...string) error {
+	resp, err := c.srv.Spreadsheets.Values.Get(c.id, "Legs").
+		ValueRenderOption("UNFORMATTED_VALUE").
+		Context(ctx).
+		Do()
+	if err != nil {
+		return fmt.Errorf("error reading Legs tab: %w", err)
+	}
+	if len(resp.Values) == 0 {
+		return fmt.Errorf("Legs tab is empty")
+	}
+	columns := map[string]int{}
+	for i, v := range resp.Values[0] {
+		columns[normalize(fmt.Sprint(v))] = i
+	}
+	legColumn, ok := columns["Leg"]
+	if !ok {
+		return fmt.Errorf("no Leg column in Legs tab")
+	}
+	rowsByLeg := map[int]int{}
+	for i, values := range resp.Values[1:] {
+		if legColumn < len(values) {
+			if leg, ok := values[legColumn].(float64); ok {
+				rowsByLeg[int(leg)] = i + 2 // rows are numbered from 1, and the first is the column names
+			}
+		}
+	}
+
+	req := &sheets.BatchUpdateValuesRequest{ValueInputOption: "RAW"}
+	for leg, values := range legs {
+		row, ok := rowsByLeg[leg]
+		if !ok {
+			return fmt.Errorf("leg %d not found in Legs tab", leg)
+		}
+		for name, value := range values {
+			column, ok := columns[name]
+			if !ok && contains(optional, name) {
+				continue
+			}
+			if !ok {
+				return fmt.Errorf("no %s column in Legs tab", name)
+			}
+			req.Data = append(req.Data, &sheets.ValueRange{
+				Range:  fmt.Sprintf("Legs!%s%d", columnName(column), row),
+				Values: [][]interface{}{{value}},
+			})
+		}
+	}
+	if len(req.Data) == 0 {
+		return nil
+	}
+	if _, err := c.srv.Spreadsheets.Values.BatchUpdate(c.id, req).Context(ctx).Do(); err != nil {
+		return fmt.Errorf("error writing Legs tab: %w", err)
+	}
+	return nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// columnName returns the A1 notation name of a zero based column index, e.g. 0 is "A" and 26 is "AA".
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// row is one row of a tab, with the cells in column order.
+type row []cell
+
+type cell struct {
+	Name  string
+	Value interface{}
+}
+
+func (r row) MarshalJSON() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString("{")
+	for i, c := range r {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		name, err := json.Marshal(c.Name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(c.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteString(":")
+		buf.Write(value)
+	}
+	buf.WriteString("}")
+	return buf.Bytes(), nil
+}
+
+// rows converts the values of a tab to rows, using the first row as the column names.
+func rows(values [][]interface{}) []row {
+	out := []row{}
+	if len(values) == 0 {
+		return out
+	}
+	var names []string
+	for _, v := range values[0] {
+		names = append(names, normalize(fmt.Sprint(v)))
+	}
+	for _, values := range values[1:] {
+		var r row
+		for i, v := range values {
+			if i >= len(names) || names[i] == "" {
+				continue
+			}
+			if s, ok := v.(string); ok && s == "" {
+				continue
+			}
+			r = append(r, cell{Name: names[i], Value: v})
+		}
+		if len(r) > 0 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// normalize removes everything but letters and digits from a column name, and any digits at the
+// start, e.g. "Top (m)" becomes "Topm".
+func normalize(name string) string {
+	var out []byte
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && len(out) > 0:
+		default:
+			continue
+		}
+		out = append(out, c)
+	}
+	return string(out)
+}
@@ -0,0 +1,183 @@
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dave/ght/project"
+	"google.golang.org/api/sheets/v4"
+)
+
+const id = "14x_OJ4mJNoHuj1LnYnyGULdE3P9kG6CwOdY1t0sv_H8"
+
+// fake is a local Sheets API server with the tabs of a spreadsheet, recording the updates it's sent.
+type fake struct {
+	tabs map[string][][]interface{}
+
+	mu      sync.Mutex
+	updates []*sheets.ValueRange
+}
+
+func (f *fake) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	prefix := "/v4/spreadsheets/" + id + "/values"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.NotFound(w, r)
+		return
+	}
+	switch path := strings.TrimPrefix(r.URL.Path, prefix); {
+	case path == ":batchGet" && r.Method == "GET":
+		resp := &sheets.BatchGetValuesResponse{SpreadsheetId: id}
+		for _, tab := range r.URL.Query()["ranges"] {
+			resp.ValueRanges = append(resp.ValueRanges, &sheets.ValueRange{Range: tab, Values: f.tabs[tab]})
+		}
+		json.NewEncoder(w).Encode(resp)
+	case path == ":batchUpdate" && r.Method == "POST":
+		var req sheets.BatchUpdateValuesRequest
+		b, _ := ioutil.ReadAll(r.Body)
+		if err := json.Unmarshal(b, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.mu.Lock()
+		f.updates = append(f.updates, req.Data...)
+		f.mu.Unlock()
+		json.NewEncoder(w).Encode(&sheets.BatchUpdateValuesResponse{SpreadsheetId: id})
+	case strings.HasPrefix(path, "/") && r.Method == "GET":
+		tab := strings.TrimPrefix(path, "/")
+		json.NewEncoder(w).Encode(&sheets.ValueRange{Range: tab, Values: f.tabs[tab]})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newClient(t *testing.T, f *fake) *Client {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	c, err := New(context.Background(), &project.Trail{
+		Sheet: "https://docs.google.com/spreadsheets/d/" + id + "/edit",
+		Sync:  project.SyncConfig{Endpoint: server.URL + "/"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestFetch(t *testing.T) {
+	c := newClient(t, &fake{tabs: map[string][][]interface{}{
+		"Legs": {
+			{"Leg", "From", "To", "Top (m)", "Vlog", "", "2nd day"},
+			{1.0, "Taplejung", "Mitlung", 1820.0, 1.0, "ignored", "x"},
+			{},
+			{"", "", ""},
+			{2.0, "Mitlung", "", 921.5, "2,3"},
+		},
+		"Waypoints": {
+			{"Leg", "Name"},
+		},
+	}})
+	b, err := c.Fetch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the columns are in sheet order, like export_to_json.js
+	want := `{
+  "Legs": [
+    {
+      "Leg": 1,
+      "From": "Taplejung",
+      "To": "Mitlung",
+      "Topm": 1820,
+      "Vlog": 1,
+      "ndday": "x"
+    },
+    {
+      "Leg": 2,
+      "From": "Mitlung",
+      "Topm": 921.5,
+      "Vlog": "2,3"
+    }
+  ],
+  "Waypoints": [],
+  "Passes": []
+}`
+	if string(b) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b, want)
+	}
+}
+
+func TestUpdateLegs(t *testing.T) {
+	header := []interface{}{"Leg", "From", "To"}
+	for i := len(header); i < 28; i++ {
+		header = append(header, "")
+	}
+	header[26] = "Length (km)" // column AA
+	header[27] = "Climb (m)"   // column AB
+	f := &fake{tabs: map[string][][]interface{}{
+		"Legs": {
+			header,
+			{1.0, "Taplejung", "Mitlung"},
+			{},
+			{2.0, "Mitlung", "Chirwa"},
+		},
+	}}
+	c := newClient(t, f)
+	err := c.UpdateLegs(context.Background(), map[int]map[string]interface{}{
+		1: {"Lengthkm": 12.5},
+		2: {"Lengthkm": 9.1, "Climbm": 640.0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]interface{}{}
+	for _, u := range f.updates {
+		got[u.Range] = u.Values[0][0]
+	}
+	want := map[string]interface{}{"Legs!AA2": 12.5, "Legs!AA4": 9.1, "Legs!AB4": 640.0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got updates %v, want %v", got, want)
+	}
+
+	if err := c.UpdateLegs(context.Background(), map[int]map[string]interface{}{3: {"Lengthkm": 1.0}}); err == nil {
+		t.Error("expected an error for a leg not in the sheet")
+	}
+	if err := c.UpdateLegs(context.Background(), map[int]map[string]interface{}{1: {"Descentm": 1.0}}); err == nil {
+		t.Error("expected an error for a column not in the sheet")
+	}
+
+	// optional columns the sheet doesn't have are left out
+	f.updates = nil
+	if err := c.UpdateLegs(context.Background(), map[int]map[string]interface{}{1: {"Lengthkm": 13.0, "Time": 4.5}}, "Time"); err != nil {
+		t.Fatal(err)
+	}
+	if len(f.updates) != 1 || f.updates[0].Range != "Legs!AA2" {
+		t.Errorf("got updates %v, want only Legs!AA2", f.updates)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	var got []string
+	for _, i := range []int{0, 25, 26, 27, 51, 52, 701, 702} {
+		got = append(got, columnName(i))
+	}
+	want := []string{"A", "Z", "AA", "AB", "AZ", "BA", "ZZ", "AAA"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for name, want := range map[string]string{"Top (m)": "Topm", "Leg": "Leg", "2nd day": "ndday", "Route rating 1-5": "Routerating15"} {
+		if got := normalize(name); got != want {
+			t.Errorf("%q: got %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Algorithm calculates the climb and descent of a route from its elevation profile. dist is the
+// distance along the route in m and ele the elevation in m of each point.
+type Algorithm struct {
+	Name        string
+	Description string // completes "Climb and descent are calculated from the GPS routes by ..."
+	climb       func(dist, ele []float64) (climb, descent float64)
+}
+
+// Algorithms are the climb algorithms that can be chosen with the -climb flag or the climb setting
+// of a trail. The first is the default.
+var Algorithms = []Algorithm{
+	{"legacy", "adding up every change in elevation, discarding steps of more than 50 m as outliers", legacy},
+	{"hysteresis", "adding up only changes in elevation of at least 5 m", hysteresis},
+	{"moving-average", "adding up the changes in elevation after averaging it over 200 m of the route", movingAverage},
+	{"savitzky-golay", "adding up the changes in elevation after smoothing it every 10 m with a 210 m Savitzky-Golay filter", savitzkyGolay},
+	{"resampled", "adding up the changes in elevation every 20 m along the route", resampled},
+}
+
+// Lookup returns the climb algorithm with the given name, or the default if name is empty.
+func Lookup(name string) (Algorithm, error) {
+	if name == "" {
+		return Algorithms[0], nil
+	}
+	var names []string
+	for _, a := range Algorithms {
+		if a.Name == name {
+			return a, nil
+		}
+		names = append(names, a.Name)
+	}
+	return Algorithm{}, fmt.Errorf("unknown climb algorithm %q (choose from %s)", name, strings.Join(names, ", "))
+}
+
+func (a Algorithm) String() string {
+	return fmt.Sprintf("%s (%s)", a.Name, a.Description)
+}
+
+// sum adds up the rises and falls of a profile.
+func sum(ele []float64) (climb, descent float64) {
+	for i := 1; i < len(ele); i++ {
+		if d := ele[i] - ele[i-1]; d > 0 {
+			climb += d
+		} else {
+			descent -= d
+		}
+	}
+	return climb, descent
+}
+
+func legacy(dist, ele []float64) (climb, descent float64) {
+	for i := 1; i < len(ele); i++ {
+		d := ele[i] - ele[i-1]
+		if math.Abs(d) > 50 {
+			// discard outlier points
+			continue
+		}
+		if d > 0 {
+			climb += d
+		} else {
+			descent -= d
+		}
+	}
+	return climb, descent
+}
+
+func hysteresis(dist, ele []float64) (climb, descent float64) {
+	const threshold = 5.0
+	if len(ele) == 0 {
+		return 0, 0
+	}
+	ref := ele[0]
+	for _, e := range ele[1:] {
+		switch {
+		case e-ref >= threshold:
+			climb += e - ref
+			ref = e
+		case ref-e >= threshold:
+			descent += ref - e
+			ref = e
+		}
+	}
+	return climb, descent
+}
+
+func movingAverage(dist, ele []float64) (climb, descent float64) {
+	const half = 100.0
+	smooth := make([]float64, len(ele))
+	from, to := 0, 0
+	var total float64
+	for i := range ele {
+		// the window is every point within half of point i
+		for to < len(ele) && dist[to] <= dist[i]+half {
+			total += ele[to]
+			to++
+		}
+		for dist[from] < dist[i]-half {
+			total -= ele[from]
+			from++
+		}
+		smooth[i] = total / float64(to-from)
+	}
+	return sum(smooth)
+}
+
+func savitzkyGolay(dist, ele []float64) (climb, descent float64) {
+	const step, m = 10.0, 10 // window of 2m+1 samples
+	samples := resample(dist, ele, step)
+	if len(samples) <= 2*m {
+		return sum(samples)
+	}
+	// coefficients of a quadratic fit, normalised by their sum
+	coeffs := make([]float64, 2*m+1)
+	var norm float64
+	for i := -m; i <= m; i++ {
+		coeffs[i+m] = float64(3*m*m + 3*m - 1 - 5*i*i)
+		norm += coeffs[i+m]
+	}
+	smooth := make([]float64, len(samples))
+	for i := range samples {
+		if i < m || i >= len(samples)-m {
+			// not enough samples on both sides to smooth the ends
+			smooth[i] = samples[i]
+			continue
+		}
+		for j, c := range coeffs {
+			smooth[i] += c * samples[i-m+j] / norm
+		}
+	}
+	return sum(smooth)
+}
+
+func resampled(dist, ele []float64) (climb, descent float64) {
+	return sum(resample(dist, ele, 20))
+}
+
+// resample interpolates the elevation every step m along the route, and at the end.
+func resample(dist, ele []float64, step float64) []float64 {
+	if len(ele) == 0 {
+		return nil
+	}
+	out := []float64{ele[0]}
+	i := 0
+	for d := step; d < dist[len(dist)-1]; d += step {
+		for dist[i+1] < d {
+			i++
+		}
+		f := (d - dist[i]) / (dist[i+1] - dist[i])
+		out = append(out, ele[i]+f*(ele[i+1]-ele[i]))
+	}
+	if len(ele) > 1 {
+		out = append(out, ele[len(ele)-1])
+	}
+	return out
+}
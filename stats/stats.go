@@ -0,0 +1,68 @@
+// Package stats calculates the length, climb and descent of a route.
+package stats
+
+import (
+	"math"
+
+	"github.com/dave/ght/geo"
+	"github.com/dave/ght/gpx"
+)
+
+// Stats are the figures in the Legs tab of the google sheet. Length is in km, Time in hours and
+// everything else in m.
+type Stats struct {
+	Length, Climb, Descent, Start, End, Top, Bottom float64
+	Time                                            float64
+}
+
+// Calc calculates the stats of a route, using a to calculate the climb and descent and m to estimate
+// the walking time. For the length, top and bottom, steps of more than 50 m vertical change are
+// discarded as outliers.
+func Calc(points []gpx.Point, a Algorithm, m TimeModel) Stats {
+	var s Stats
+	dist := make([]float64, len(points))
+	ele := make([]float64, len(points))
+	for i, current := range points {
+		ele[i] = current.Ele
+		if i == 0 {
+			s.Start = current.Ele
+			s.Top = current.Ele
+			s.Bottom = current.Ele
+		}
+		if i == len(points)-1 {
+			s.End = current.Ele
+		}
+		if i == 0 {
+			continue
+		}
+
+		// work out distance delta
+		previous := points[i-1]
+		horizontal := geo.Distance(current.Lat, current.Lon, previous.Lat, previous.Lon)
+		dist[i] = dist[i-1] + horizontal*1000
+
+		// work out elevation delta
+		vertical := math.Abs(current.Ele - previous.Ele)
+
+		if vertical > 50 {
+			// discard outlier points
+			continue
+		}
+
+		verticalkm := vertical / 1000.0
+
+		total := math.Sqrt(horizontal*horizontal + verticalkm*verticalkm)
+
+		if current.Ele > s.Top {
+			s.Top = current.Ele
+		}
+		if current.Ele < s.Bottom {
+			s.Bottom = current.Ele
+		}
+
+		s.Length += total
+	}
+	s.Climb, s.Descent = a.climb(dist, ele)
+	s.Time = m.time(dist, ele, s.Climb)
+	return s
+}
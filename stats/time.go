@@ -0,0 +1,91 @@
+package stats
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// TimeModel estimates the walking time of a route in hours from its elevation profile. dist is the
+// distance along the route in m and ele the elevation in m of each point, and climb is from the
+// climb algorithm.
+type TimeModel struct {
+	Name        string
+	Description string // completes "Walking times are estimated using ..."
+	time        func(dist, ele []float64, climb float64) float64
+}
+
+// TimeModels are the walking time models that can be chosen with the -walking flag or the walking
+// setting of a trail. The first is the default.
+var TimeModels = []TimeModel{
+	{"naismith", "Naismith's rule (5 km/h plus an hour for every 600 m of climb) with Langmuir's corrections for descents", naismith},
+	{"tobler", "Tobler's hiking function over the slope every 20 m", tobler},
+}
+
+// LookupTimeModel returns the walking time model with the given name, or the default if name is
+// empty.
+func LookupTimeModel(name string) (TimeModel, error) {
+	if name == "" {
+		return TimeModels[0], nil
+	}
+	var names []string
+	for _, m := range TimeModels {
+		if m.Name == name {
+			return m, nil
+		}
+		names = append(names, m.Name)
+	}
+	return TimeModel{}, fmt.Errorf("unknown walking time model %q (choose from %s)", name, strings.Join(names, ", "))
+}
+
+func (m TimeModel) String() string {
+	return fmt.Sprintf("%s (%s)", m.Name, m.Description)
+}
+
+func naismith(dist, ele []float64, climb float64) float64 {
+	if len(dist) == 0 {
+		return 0
+	}
+	hours := dist[len(dist)-1]/5000 + climb/600
+
+	// Langmuir: take off 10 minutes for every 300 m of descent at 5-12 degrees, and add 10 minutes
+	// for every 300 m of descent steeper than 12 degrees. The slope is measured every 20 m so GPS
+	// noise doesn't make flat ground look steep.
+	const step = 20.0
+	samples := resample(dist, ele, step)
+	gentle, steep := math.Tan(5*math.Pi/180), math.Tan(12*math.Pi/180)
+	for i := 1; i < len(samples); i++ {
+		drop := samples[i-1] - samples[i]
+		switch slope := drop / step; {
+		case slope > steep:
+			hours += drop / 300 / 6
+		case slope >= gentle:
+			hours -= drop / 300 / 6
+		}
+	}
+	return hours
+}
+
+func tobler(dist, ele []float64, climb float64) float64 {
+	if len(dist) == 0 {
+		return 0
+	}
+	// the slope is measured every 20 m like naismith, because the slope between each pair of points
+	// of a dense track is mostly GPS noise
+	const step = 20.0
+	samples := resample(dist, ele, step)
+	var hours float64
+	for i := 1; i < len(samples); i++ {
+		dx := step
+		if i == len(samples)-1 {
+			dx = dist[len(dist)-1] - float64(i-1)*step
+		}
+		if dx <= 0 {
+			continue
+		}
+		// walking speed in km/h
+		speed := 6 * math.Exp(-3.5*math.Abs((samples[i]-samples[i-1])/dx+0.05))
+		hours += dx / 1000 / speed
+	}
+	return hours
+}
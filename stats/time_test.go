@@ -0,0 +1,66 @@
+package stats
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// profile returns points every step m along length m of a route with a constant slope, with
+// uniform noise of up to noise m added to each elevation.
+func profile(length, step, slope, noise float64) (dist, ele []float64) {
+	r := rand.New(rand.NewSource(1))
+	for d := 0.0; d <= length; d += step {
+		dist = append(dist, d)
+		ele = append(ele, 1000+d*slope+(r.Float64()*2-1)*noise)
+	}
+	return dist, ele
+}
+
+func TestTimeModels(t *testing.T) {
+	for _, test := range []struct {
+		name                   string
+		dist, ele              []float64
+		naismith, tobler       float64
+		naismithTol, toblerTol float64
+	}{
+		{name: "empty"},
+		// 10 km of flat ground: 2 h, and 10 km at Tobler's 5.04 km/h on the flat
+		{"flat", []float64{0, 10000}, []float64{1000, 1000}, 2, 10 / (6 * math.Exp(-3.5*0.05)), 1e-9, 1e-9},
+		// 5 km at 10%: 1 h plus 500 m of climb, and Tobler's 3.55 km/h, however dense the points
+		{"sparse climb", nil, nil, 1 + 500.0/600, 5 / (6 * math.Exp(-3.5*0.15)), 1e-9, 1e-9},
+		{"dense climb", nil, nil, 1 + 500.0/600, 5 / (6 * math.Exp(-3.5*0.15)), 1e-9, 1e-9},
+		// GPS noise on a dense track mustn't make flat ground look steep (the resampled climb still
+		// counts a little of it)
+		{"noisy flat", nil, nil, 2, 10 / (6 * math.Exp(-3.5*0.05)), 0.5, 0.25},
+	} {
+		dist, ele := test.dist, test.ele
+		switch test.name {
+		case "sparse climb":
+			dist, ele = profile(5000, 500, 0.1, 0)
+		case "dense climb":
+			dist, ele = profile(5000, 2, 0.1, 0)
+		case "noisy flat":
+			dist, ele = profile(10000, 2, 0, 1.5)
+		}
+		climb, _ := resampled(dist, ele)
+		if got := naismith(dist, ele, climb); math.Abs(got-test.naismith) > test.naismithTol {
+			t.Errorf("%s: naismith got %.3f h, want %.3f h", test.name, got, test.naismith)
+		}
+		if got := tobler(dist, ele, climb); math.Abs(got-test.tobler) > test.toblerTol {
+			t.Errorf("%s: tobler got %.3f h, want %.3f h", test.name, got, test.tobler)
+		}
+	}
+}
+
+func TestLookupTimeModel(t *testing.T) {
+	if m, err := LookupTimeModel(""); err != nil || m.Name != "naismith" {
+		t.Errorf("got %v %v, want the default naismith", m.Name, err)
+	}
+	if m, err := LookupTimeModel("tobler"); err != nil || m.Name != "tobler" {
+		t.Errorf("got %v %v", m.Name, err)
+	}
+	if _, err := LookupTimeModel("scarf"); err == nil {
+		t.Error("expected an error for an unknown model")
+	}
+}